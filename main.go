@@ -1,34 +1,94 @@
 package main
 
 import (
+	"encoding/json"
 	"flag"
+	"io/ioutil"
+	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"k8s.io/client-go/util/homedir"
 
 	"github.com/homedepot/k8s-global-objects/runner"
 	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 var (
-	kubeconfig  string
-	runInterval time.Duration
-	runOnce     bool
-	debug       bool
+	kubeconfig        string
+	runInterval       time.Duration
+	runOnce           bool
+	debug             bool
+	excludeNamespaces string
+	resources         string
+	conflictPolicy    string
+	orphanGrace       time.Duration
+	httpAddress       string
+	maxRetries        int
+	retryBackoff      time.Duration
+	dryRun            bool
+	serverSideApply   bool
+	compareFields     string
+	propagate         string
+	propagatePrefixes string
+	duplicatePolicy   string
+	sourceNamespaces  string
+	sourceNsFile      string
+	sourceNsSelector  string
+	sourceDirs        string
+	syncStatus        bool
+	duplicateNs       string
+	leaderElect       bool
+	leaseNamespace    string
+	leaseName         string
+	leaseDuration     time.Duration
+	renewDeadline     time.Duration
+	retryPeriod       time.Duration
 )
 
 func init() {
 	flag.StringVar(&kubeconfig, "kubeconfig", homedir.HomeDir()+"/.kube/config", "KUBECONFIG location")
-	flag.DurationVar(&runInterval, "runinterval", time.Second*60, "interval to kick off sync")
+	flag.DurationVar(&runInterval, "runinterval", time.Second*60, "interval to kick off a full resync")
 	flag.BoolVar(&runOnce, "runonce", false, "Run App once")
 	flag.BoolVar(&debug, "debug", false, "Debug")
+	flag.StringVar(&excludeNamespaces, "exclude-namespaces", "", "Comma separated namespace names or glob patterns that never receive global objects")
+	flag.StringVar(&conflictPolicy, "conflict-policy", string(runner.ConflictSkip), "What to do with same named objects not created by the runner: skip, adopt or overwrite")
+	flag.DurationVar(&orphanGrace, "orphan-grace-period", 0, "How long a copy stays orphaned, its global object deleted or no longer annotated, before it is removed")
+	flag.StringVar(&resources, "resources", "", "Comma separated additional namespaced resources to replicate as group/version/resource, or version/resource for the core group")
+	flag.BoolVar(&dryRun, "dry-run", false, "Log the planned creates, updates and deletes instead of making them, with -runonce the plan is printed as JSON")
+	flag.StringVar(&compareFields, "compare-fields", strings.Join(runner.CompareFields, ","), "Comma separated fields drift detection compares between a global object and its copies")
+	flag.StringVar(&sourceNamespaces, "source-namespaces", "", "Comma separated namespace names or glob patterns allowed to publish global objects, every namespace when empty")
+	flag.StringVar(&sourceNsFile, "source-namespaces-file", "", "File listing namespace names or glob patterns allowed to publish global objects, one per line, added to -source-namespaces")
+	flag.StringVar(&sourceNsSelector, "source-namespace-selector", "", "Label selector namespaces must match to publish global objects")
+	flag.StringVar(&sourceDirs, "source-dirs", "", "Comma separated directories whose ConfigMap and Secret manifests are replicated as global objects, such as a git-sync checkout")
+	flag.BoolVar(&syncStatus, "sync-status", true, "Annotate global objects with the status of their copies after every sync")
+	flag.StringVar(&duplicatePolicy, "duplicate-policy", string(runner.DuplicateOldest), "Which global object is replicated when several namespaces publish the same name: oldest, priority or namespaces")
+	flag.StringVar(&duplicateNs, "duplicate-namespaces", "", "Comma separated source namespaces in order of precedence for -duplicate-policy namespaces")
+	flag.StringVar(&propagate, "propagate", string(runner.PropagateNever), "Which labels and annotations of a global object its copies get: never, prefix or all")
+	flag.StringVar(&propagatePrefixes, "propagate-prefixes", "", "Comma separated label and annotation key prefixes copied with -propagate prefix")
+	flag.BoolVar(&serverSideApply, "server-side-apply", false, "Server-side apply updates of ConfigMap and Secret copies as field manager k8s-global-objects, needs a server supporting it")
+	flag.IntVar(&maxRetries, "max-retries", 5, "How often a conflicting write, a failed namespace or sync is retried before waiting for the next resync")
+	flag.DurationVar(&retryBackoff, "retry-backoff", 100*time.Millisecond, "First delay before queueing a failed namespace or sync again, doubled on every attempt")
+	flag.StringVar(&httpAddress, "http-address", ":8080", "Address to serve /metrics, /healthz and /readyz on, empty to disable")
+	flag.BoolVar(&leaderElect, "leader-elect", false, "Only sync while holding the leader election lease, for running multiple replicas")
+	flag.StringVar(&leaseNamespace, "leader-elect-namespace", "k8s-global-objects", "Namespace of the leader election lease")
+	flag.StringVar(&leaseName, "leader-elect-name", "k8s-global-objects", "Name of the leader election lease")
+	flag.DurationVar(&leaseDuration, "leader-elect-lease-duration", 15*time.Second, "How long standbys wait before taking over a lease that was not renewed")
+	flag.DurationVar(&renewDeadline, "leader-elect-renew-deadline", 10*time.Second, "How long the leader keeps retrying to renew the lease before giving up")
+	flag.DurationVar(&retryPeriod, "leader-elect-retry-period", 2*time.Second, "How long to wait between attempts to acquire or renew the lease")
 	flag.Parse()
 
 	log.SetOutput(os.Stdout)
+	// stdout is left to the JSON plan
+	if dryRun && runOnce {
+		log.SetOutput(os.Stderr)
+	}
 	log.SetLevel(log.InfoLevel)
 
 	customFormatter := new(log.TextFormatter)
@@ -48,6 +108,31 @@ func init() {
 	log.Debugf("Flag runinterval: %v", runInterval)
 	log.Debugf("Flag runOnce: %v", runOnce)
 	log.Debugf("Flag debug: %v", debug)
+	log.Debugf("Flag exclude-namespaces: %v", excludeNamespaces)
+	log.Debugf("Flag resources: %v", resources)
+	log.Debugf("Flag conflict-policy: %v", conflictPolicy)
+	log.Debugf("Flag orphan-grace-period: %v", orphanGrace)
+	log.Debugf("Flag dry-run: %v", dryRun)
+	log.Debugf("Flag server-side-apply: %v", serverSideApply)
+	log.Debugf("Flag compare-fields: %v", compareFields)
+	log.Debugf("Flag source-namespaces: %v", sourceNamespaces)
+	log.Debugf("Flag source-namespaces-file: %v", sourceNsFile)
+	log.Debugf("Flag source-namespace-selector: %v", sourceNsSelector)
+	log.Debugf("Flag source-dirs: %v", sourceDirs)
+	log.Debugf("Flag sync-status: %v", syncStatus)
+	log.Debugf("Flag duplicate-policy: %v", duplicatePolicy)
+	log.Debugf("Flag duplicate-namespaces: %v", duplicateNs)
+	log.Debugf("Flag propagate: %v", propagate)
+	log.Debugf("Flag propagate-prefixes: %v", propagatePrefixes)
+	log.Debugf("Flag max-retries: %v", maxRetries)
+	log.Debugf("Flag retry-backoff: %v", retryBackoff)
+	log.Debugf("Flag http-address: %v", httpAddress)
+	log.Debugf("Flag leader-elect: %v", leaderElect)
+	log.Debugf("Flag leader-elect-namespace: %v", leaseNamespace)
+	log.Debugf("Flag leader-elect-name: %v", leaseName)
+	log.Debugf("Flag leader-elect-lease-duration: %v", leaseDuration)
+	log.Debugf("Flag leader-elect-renew-deadline: %v", renewDeadline)
+	log.Debugf("Flag leader-elect-retry-period: %v", retryPeriod)
 }
 
 func main() {
@@ -72,6 +157,64 @@ func main() {
 	if err != nil {
 		log.WithError(err)
 	}
+	client.Dynamic, err = dynamic.NewForConfig(config)
+	if err != nil {
+		log.WithError(err)
+	}
+
+	// additional resources to replicate
+	gvrs := make([]schema.GroupVersionResource, 0)
+	for _, resource := range splitList(resources) {
+		gvr, err := runner.ParseGroupVersionResource(resource)
+		if err != nil {
+			log.Fatal(err)
+		}
+		gvrs = append(gvrs, gvr)
+	}
+
+	policy, err := runner.ParseConflictPolicy(conflictPolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	propagation, err := runner.ParsePropagationPolicy(propagate)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// namespaces allowed to publish global objects
+	sources := splitList(sourceNamespaces)
+	if sourceNsFile != "" {
+		listed, err := readList(sourceNsFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// an empty file must not open publishing to every namespace
+		if len(listed) == 0 {
+			log.Fatalf("%v lists no source namespaces", sourceNsFile)
+		}
+		sources = append(sources, listed...)
+	}
+
+	// global objects kept outside the cluster
+	providers := make([]runner.SourceProvider, 0)
+	for _, dir := range splitList(sourceDirs) {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			log.Fatalf("source directory %v not found", dir)
+		}
+		providers = append(providers, runner.NewDirectorySource(dir))
+	}
+
+	duplicates, err := runner.ParseDuplicatePolicy(duplicatePolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// leader election identity, the pod name in kubernetes
+	identity, err := os.Hostname()
+	if err != nil {
+		log.Fatal(err)
+	}
 
 	// start runner
 	var run *runner.Runner
@@ -81,17 +224,59 @@ func main() {
 			RunInterval: runInterval,
 			Debug:       debug,
 			Once:        runOnce,
+
+			ExcludeNamespaces:       splitList(excludeNamespaces),
+			Resources:               gvrs,
+			ConflictPolicy:          policy,
+			OrphanGracePeriod:       orphanGrace,
+			MaxRetries:              maxRetries,
+			RetryBackoff:            retryBackoff,
+			DryRun:                  dryRun,
+			ServerSideApply:         serverSideApply,
+			CompareFields:           splitList(compareFields),
+			PropagationPolicy:       propagation,
+			PropagatePrefixes:       splitList(propagatePrefixes),
+			SourceNamespaces:        sources,
+			SourceNamespaceSelector: sourceNsSelector,
+			SourceProviders:         providers,
+			SyncStatus:              syncStatus,
+			DuplicatePolicy:         duplicates,
+			DuplicateNamespaces:     splitList(duplicateNs),
+
+			LeaderElect:    leaderElect,
+			LeaseNamespace: leaseNamespace,
+			LeaseName:      leaseName,
+			LeaseDuration:  leaseDuration,
+			RenewDeadline:  renewDeadline,
+			RetryPeriod:    retryPeriod,
+			Identity:       identity,
 		}
 
 		log.Info("Starting K8S Global Objects Runner")
 		run = runner.NewRunner(runnerConfig)
 
+		// serving metrics and probes
+		if httpAddress != "" {
+			http.Handle("/metrics", runner.MetricsHandler())
+			http.Handle("/healthz", run.HealthzHandler())
+			http.Handle("/readyz", run.ReadyzHandler())
+			go func() {
+				log.Fatal(http.ListenAndServe(httpAddress, nil))
+			}()
+		}
+
 		err = run.Init()
 		if err != nil {
 			log.Fatal(err)
 		}
 
 		err := run.Start()
+		// a sync with namespace errors still planned the other namespaces
+		if dryRun && runOnce && run.LastPlan() != nil {
+			if err := json.NewEncoder(os.Stdout).Encode(run.LastPlan()); err != nil {
+				log.Fatal(err)
+			}
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -99,3 +284,31 @@ func main() {
 
 	defer run.Close()
 }
+
+// splitList turns a comma separated flag value into a list, dropping empty items
+func splitList(value string) []string {
+	list := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// readList reads one item per line, skipping blank lines and # comments
+func readList(file string) ([]string, error) {
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]string, 0)
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			list = append(list, line)
+		}
+	}
+	return list, nil
+}
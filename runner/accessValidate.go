@@ -5,27 +5,78 @@ import (
 	authorizationv1 "k8s.io/api/authorization/v1"
 )
 
-var validateAccess = []struct {
+type accessCheck struct {
 	verb     string
+	group    string
 	resource string
-}{
+}
+
+var validateAccess = []accessCheck{
 	{verb: "list", resource: "namespaces"},
+	{verb: "watch", resource: "namespaces"},
 	{verb: "get", resource: "configmaps"},
 	{verb: "list", resource: "configmaps"},
+	{verb: "watch", resource: "configmaps"},
 	{verb: "create", resource: "configmaps"},
 	{verb: "update", resource: "configmaps"},
 	{verb: "delete", resource: "configmaps"},
 	{verb: "get", resource: "secrets"},
 	{verb: "list", resource: "secrets"},
+	{verb: "watch", resource: "secrets"},
 	{verb: "create", resource: "secrets"},
 	{verb: "update", resource: "secrets"},
 	{verb: "delete", resource: "secrets"},
 }
 
+// verbs needed on every additional resource
+var validateResourceVerbs = []string{"get", "list", "watch", "create", "update", "delete"}
+
+// verbs a dry run does not need on replicated objects
+var writeVerbs = map[string]bool{"create": true, "update": true, "patch": true, "delete": true}
+
+// checks needed to server-side apply and to write the sync status of global objects
+var validatePatchAccess = []accessCheck{
+	{verb: "patch", resource: "configmaps"},
+	{verb: "patch", resource: "secrets"},
+}
+
+// verbs needed on the leader election lease
+var validateLeaseVerbs = []string{"get", "create", "update"}
+
+func (r *Runner) accessChecks() []accessCheck {
+	checks := make([]accessCheck, 0, len(validateAccess)+len(r.resources)*len(validateResourceVerbs))
+	for _, check := range validateAccess {
+		if r.dryRun && writeVerbs[check.verb] {
+			continue
+		}
+		checks = append(checks, check)
+	}
+	if (r.serverSideApply || r.syncStatus) && !r.dryRun {
+		checks = append(checks, validatePatchAccess...)
+	}
+	for _, gvr := range r.resources {
+		for _, verb := range validateResourceVerbs {
+			if r.dryRun && writeVerbs[verb] {
+				continue
+			}
+			checks = append(checks, accessCheck{verb: verb, group: gvr.Group, resource: gvr.Resource})
+		}
+		if r.syncStatus && !r.dryRun {
+			checks = append(checks, accessCheck{verb: "patch", group: gvr.Group, resource: gvr.Resource})
+		}
+	}
+	if r.leaderElect {
+		for _, verb := range validateLeaseVerbs {
+			checks = append(checks, accessCheck{verb: verb, group: "coordination.k8s.io", resource: "leases"})
+		}
+	}
+	return checks
+}
+
 func (r *Runner) ValidateMyAccess() (bool, error) {
-	for _, tt := range validateAccess {
-		res, err := r.CanIdo(tt.verb, tt.resource)
-		log.Debugf("Result Action: %v Resouce: %v Allowed: %v", tt.verb, tt.resource, res)
+	for _, tt := range r.accessChecks() {
+		res, err := r.canIdo(tt.verb, tt.group, tt.resource)
+		log.Debugf("Result Action: %v Resouce: %v Allowed: %v", tt.verb, groupResource(tt.group, tt.resource), res)
 		if err != nil {
 			return false, err
 		}
@@ -37,11 +88,16 @@ func (r *Runner) ValidateMyAccess() (bool, error) {
 }
 
 func (r *Runner) CanIdo(verb string, resource string) (bool, error) {
-	log.Infof("Validating Action: %v in Resource: %v", verb, resource)
+	return r.canIdo(verb, "", resource)
+}
+
+func (r *Runner) canIdo(verb string, group string, resource string) (bool, error) {
+	log.Infof("Validating Action: %v in Resource: %v", verb, groupResource(group, resource))
 	ssar := &authorizationv1.SelfSubjectAccessReview{
 		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
 			ResourceAttributes: &authorizationv1.ResourceAttributes{
 				Verb:     verb,
+				Group:    group,
 				Resource: resource,
 			},
 		},
@@ -52,3 +108,10 @@ func (r *Runner) CanIdo(verb string, resource string) (bool, error) {
 	}
 	return ssar.Status.Allowed, nil
 }
+
+func groupResource(group string, resource string) string {
+	if group == "" {
+		return resource
+	}
+	return resource + "." + group
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 	authorizationv1 "k8s.io/api/authorization/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/kubernetes/fake"
 	k8stesting "k8s.io/client-go/testing"
 )
@@ -76,3 +77,48 @@ func TestAccess_False(t *testing.T) {
 	require.Error(err)
 	require.False(allowed)
 }
+
+func TestAccess_Resources(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+
+	k8s := fake_clientset()
+	k8s.Clientset.(*fake.Clientset).Fake.AddReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		sar := action.(k8stesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+		// only networkpolicies updates are denied
+		allowed := !(sar.Spec.ResourceAttributes.Group == "networking.k8s.io" && sar.Spec.ResourceAttributes.Verb == "update")
+		return true, &authorizationv1.SelfSubjectAccessReview{
+			Status: authorizationv1.SubjectAccessReviewStatus{Allowed: allowed},
+		}, nil
+	})
+
+	config.Client = k8s
+	config.Resources = []schema.GroupVersionResource{limitRangeResource}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	allowed, err := runr.ValidateMyAccess()
+	require.NoError(err)
+	require.True(allowed)
+
+	config.Resources = append(config.Resources, schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"})
+	runr = runner.NewRunner(&config)
+	defer runr.Close()
+
+	allowed, err = runr.ValidateMyAccess()
+	require.NoError(err)
+	require.False(allowed)
+
+	// a dry run only reads
+	config.DryRun = true
+	runr = runner.NewRunner(&config)
+	defer runr.Close()
+
+	allowed, err = runr.ValidateMyAccess()
+	require.NoError(err)
+	require.True(allowed)
+}
@@ -0,0 +1,67 @@
+package runner
+
+import (
+	"path"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// sourceRestricted reports if only some namespaces may publish global objects
+func (r *Runner) sourceRestricted() bool {
+	return len(r.sourceNamespaces) > 0 || r.sourceNamespaceSelector != ""
+}
+
+// sourceAllowed reports if global objects found in the namespace are replicated
+func (r *Runner) sourceAllowed(namespace v1.Namespace) bool {
+	if len(r.sourceNamespaces) > 0 {
+		listed := false
+		for _, pattern := range r.sourceNamespaces {
+			// patterns are validated in Init
+			if matched, _ := path.Match(pattern, namespace.Name); matched {
+				listed = true
+				break
+			}
+		}
+		if !listed {
+			return false
+		}
+	}
+
+	if r.sourceNamespaceSelector != "" {
+		// the selector is validated in Init
+		selector, err := labels.Parse(r.sourceNamespaceSelector)
+		if err != nil || !selector.Matches(labels.Set(namespace.Labels)) {
+			return false
+		}
+	}
+	return true
+}
+
+// blockSources records the namespaces not allowed to publish global objects in this sync,
+// copies of their objects are removed like orphans
+func (r *Runner) blockSources(nsList []v1.Namespace) {
+	r.blockedSources = make(map[string]bool)
+	if !r.sourceRestricted() {
+		return
+	}
+	for _, namespace := range nsList {
+		if !r.sourceAllowed(namespace) {
+			r.blockedSources[namespace.Name] = true
+		}
+	}
+}
+
+// reportBlockedSource reports a global object annotated in a namespace not allowed to publish them
+func (r *Runner) reportBlockedSource(kind string, object metav1.Object) {
+	blockedSources.WithLabelValues(kind).Inc()
+	log.Warnf("Ignoring %v %v/%v - namespace %v is not allowed to publish global objects",
+		kind, object.GetNamespace(), object.GetName(), object.GetNamespace())
+	if o, ok := object.(runtime.Object); ok {
+		r.event(o, v1.EventTypeWarning, reasonSourceNotAllowed, "Namespace %v is not allowed to publish global objects, %v annotation ignored",
+			object.GetNamespace(), annotationKey)
+	}
+}
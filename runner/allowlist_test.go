@@ -0,0 +1,108 @@
+package runner_test
+
+import (
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestAllowlist_SourceNamespaces(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	recorder := record.NewFakeRecorder(100)
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	config.Recorder = recorder
+	config.SourceNamespaces = []string{"def*"}
+
+	allowed := configmap
+	allowed.ObjectMeta = metav1.ObjectMeta{Name: "allowed-global", Namespace: "default", Annotations: map[string]string{"MakeGlobal": "true"}}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("default").Create(&allowed)
+
+	blocked := secret
+	blocked.ObjectMeta = metav1.ObjectMeta{Name: "blocked-global", Namespace: "myapp", UID: "1234", Annotations: map[string]string{"MakeGlobal": "true"}}
+	_, _ = config.Client.Clientset.CoreV1().Secrets("myapp").Create(&blocked)
+
+	// copy made before the namespace lost the right to publish
+	stale := v1.Secret{ObjectMeta: copyMeta(blocked.ObjectMeta), Data: blocked.Data}
+	stale.Namespace = appNamespace
+	_, _ = config.Client.Clientset.CoreV1().Secrets(appNamespace).Create(&stale)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Get(allowed.Name, metav1.GetOptions{})
+	require.NoError(err)
+	_, err = config.Client.Clientset.CoreV1().Secrets("default").Get(blocked.Name, metav1.GetOptions{})
+	require.Error(err)
+	_, err = config.Client.Clientset.CoreV1().Secrets(appNamespace).Get(blocked.Name, metav1.GetOptions{})
+	require.Error(err)
+
+	require.Contains(recordedEvents(recorder), "Warning SourceNamespaceNotAllowed Namespace myapp is not allowed to publish global objects, MakeGlobal annotation ignored")
+}
+
+func TestAllowlist_SourceNamespaceSelector(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	config.SourceNamespaceSelector = "global-objects/publisher=true"
+
+	_, err := config.Client.Clientset.CoreV1().Namespaces().Create(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "platform",
+			Labels: map[string]string{"global-objects/publisher": "true"},
+		},
+	})
+	require.NoError(err)
+
+	published := configmap
+	published.ObjectMeta = metav1.ObjectMeta{Name: "platform-global", Namespace: "platform", Annotations: map[string]string{"MakeGlobal": "true"}}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("platform").Create(&published)
+
+	ignored := configmap
+	ignored.ObjectMeta = metav1.ObjectMeta{Name: "team-global", Namespace: "myapp", Annotations: map[string]string{"MakeGlobal": "true"}}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&ignored)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err = runr.Start()
+	require.NoError(err)
+
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get(published.Name, metav1.GetOptions{})
+	require.NoError(err)
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get(ignored.Name, metav1.GetOptions{})
+	require.Error(err)
+}
+
+func TestAllowlist_Init_BadSelector(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.SourceNamespaceSelector = "publisher in (true"
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Init()
+	require.Error(err)
+	require.Contains(err.Error(), "source namespace selector")
+}
@@ -0,0 +1,65 @@
+package runner
+
+import (
+	"encoding/json"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// fieldManager owns the fields the runner sets when using server-side apply
+	fieldManager = "k8s-global-objects"
+	// applyPatchType is the server-side apply content type, the client libraries predate it
+	applyPatchType types.PatchType = "application/apply-patch+yaml"
+)
+
+// applyCore server-side applies a core group object, taking over the fields it sets from other managers,
+// only copies the runner may write are applied, creates go through Create
+func (r *Runner) applyCore(resource string, namespace string, name string, object runtime.Object, into runtime.Object) error {
+	// JSON is valid YAML
+	body, err := json.Marshal(object)
+	if err != nil {
+		return err
+	}
+	return r.client.Clientset.CoreV1().RESTClient().Patch(applyPatchType).
+		Namespace(namespace).
+		Resource(resource).
+		Name(name).
+		Param("fieldManager", fieldManager).
+		Param("force", "true").
+		Body(body).
+		Do().
+		Into(into)
+}
+
+// mergeMetadata sets the labels and annotations of desired on live, keeping the ones other controllers added
+// and removing the ones the runner propagated before but no longer does
+func mergeMetadata(live metav1.Object, desired metav1.Object) {
+	staleLabels, staleAnnotations := stalePropagated(live, desired)
+
+	labels := live.GetLabels()
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	for _, key := range staleLabels {
+		delete(labels, key)
+	}
+	for key, value := range desired.GetLabels() {
+		labels[key] = value
+	}
+	live.SetLabels(labels)
+
+	annotations := live.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	for _, key := range staleAnnotations {
+		delete(annotations, key)
+	}
+	for key, value := range desired.GetAnnotations() {
+		annotations[key] = value
+	}
+	live.SetAnnotations(annotations)
+}
@@ -0,0 +1,133 @@
+package runner_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+func TestApply_Update_KeepsForeignMetadata(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+
+	// copy another controller added to
+	existing := configmap
+	existing.ObjectMeta.Name = "storeconfig-global"
+	existing.ObjectMeta.Labels = map[string]string{"CreatedBy": "k8s-global-objects", "team": "payments"}
+	existing.ObjectMeta.Annotations = map[string]string{"checksum/config": "1234"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("default").Create(&existing)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	global := configmap
+	global.ObjectMeta.Name = existing.Name
+	global.ObjectMeta.Namespace = "myapp"
+	global.Data = map[string]string{"updateKey": "updateData"}
+	err := runr.UpdateConfigMap("default", global)
+	require.NoError(err)
+
+	updated, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(global.Data, updated.Data)
+	require.Equal("payments", updated.Labels["team"])
+	require.Equal("k8s-global-objects", updated.Labels["CreatedBy"])
+	require.Equal("1234", updated.Annotations["checksum/config"])
+	require.Equal("myapp", updated.Annotations["GlobalSourceNamespace"])
+}
+
+func TestApply_ServerSideApply(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	requests := make([]*http.Request, 0)
+	bodies := make([]v1.ConfigMap, 0)
+	handMade := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		// no override ConfigMaps
+		if req.Method == http.MethodGet {
+			http.NotFound(w, req)
+			return
+		}
+		requests = append(requests, req)
+		raw, _ := ioutil.ReadAll(req.Body)
+		applied := v1.ConfigMap{}
+		_ = json.Unmarshal(raw, &applied)
+		bodies = append(bodies, applied)
+
+		w.Header().Set("Content-Type", "application/json")
+		if req.Method == http.MethodPost && handMade {
+			w.WriteHeader(http.StatusConflict)
+			_ = json.NewEncoder(w).Encode(metav1.Status{
+				TypeMeta: metav1.TypeMeta{Kind: "Status", APIVersion: "v1"},
+				Status:   metav1.StatusFailure,
+				Reason:   metav1.StatusReasonAlreadyExists,
+				Code:     http.StatusConflict,
+			})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(applied)
+	}))
+	defer server.Close()
+
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: server.URL})
+	require.NoError(err)
+
+	config := *runner.DefaultConfig()
+	config.Client = &runner.K8S{Clientset: clientset}
+	config.ServerSideApply = true
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	global := configmap
+	global.ObjectMeta.Name = "storeconfig-global"
+	global.ObjectMeta.Namespace = "myapp"
+	require.NoError(runr.CreateConfigMap("default", global))
+	require.NoError(runr.UpdateConfigMap("default", global))
+
+	require.Len(requests, 2)
+	// creates are never applied
+	require.Equal("POST", requests[0].Method)
+	require.Equal("/api/v1/namespaces/default/configmaps", requests[0].URL.Path)
+
+	req := requests[1]
+	require.Equal("PATCH", req.Method)
+	require.Equal("/api/v1/namespaces/default/configmaps/storeconfig-global", req.URL.Path)
+	require.Equal("application/apply-patch+yaml", req.Header.Get("Content-Type"))
+	require.Equal("k8s-global-objects", req.URL.Query().Get("fieldManager"))
+	require.Equal("true", req.URL.Query().Get("force"))
+
+	// only the fields the runner owns
+	for _, body := range bodies {
+		require.Equal("ConfigMap", body.Kind)
+		require.Equal(global.Data, body.Data)
+		require.Equal("k8s-global-objects", body.Labels["CreatedBy"])
+		require.Equal("myapp", body.Annotations["GlobalSourceNamespace"])
+		require.Empty(body.ResourceVersion)
+	}
+
+	// a hand made object the informer cache has not seen yet is left alone
+	handMade = true
+	configMapMaps := map[string]*runner.NamespaceConfigMaps{
+		"default": {Configmaps: []v1.ConfigMap{}},
+	}
+	require.NoError(runr.AddAnnotatedConfigMap(configMapMaps, "default", global))
+	require.Len(requests, 3)
+	require.Equal("POST", requests[2].Method)
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 var configmap = v1.ConfigMap{
@@ -96,3 +97,25 @@ func TestEngine_checkAnnotationKey_remove(t *testing.T) {
 	require.Equal("false", res)
 	require.NoError(err)
 }
+
+func TestEngine_checkNamespaceSelector(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	configmap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	res, err := checkNamespaceSelector(&configmap)
+	require.NoError(err)
+	require.True(res.Empty())
+
+	configmap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true", "MakeGlobalNamespaceSelector": "team,environment!=prod"}
+	res, err = checkNamespaceSelector(&configmap)
+	require.NoError(err)
+	require.True(res.Matches(labels.Set{"team": "a"}))
+	require.False(res.Matches(labels.Set{"team": "a", "environment": "prod"}))
+	require.False(res.Matches(labels.Set{}))
+
+	secret.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true", "MakeGlobalNamespaceSelector": "team in (a"}
+	_, err = checkNamespaceSelector(&secret)
+	require.Error(err)
+	require.False(namespaceSelected(&secret, v1.Namespace{}))
+}
@@ -16,44 +16,100 @@ func (r *Runner) ConfigMapList(namespace string) (configmaps *v1.ConfigMapList,
 }
 
 func (r *Runner) CreateConfigMap(namespace string, from v1.ConfigMap) (err error) {
-	log.Debugf("Creating ConfigMap %v in namespace %v", from.Name, namespace)
+	log.Debugf("Creating ConfigMap %v in namespace %v", copyName(&from), namespace)
 
-	configMap := createConfigMapObject(from)
-	configMap.ObjectMeta.Namespace = namespace
+	configMap, err := r.createConfigMapObject(namespace, from)
+	if err != nil {
+		return err
+	}
 
-	_, err = r.client.Clientset.CoreV1().ConfigMaps(namespace).Create(configMap)
+	if r.dryRun {
+		r.planChange("create", "ConfigMap", namespace, &from, nil, configMapContent(configMap))
+		return nil
+	}
+
+	// never applied, a forced apply would take over a same named object the informer cache has not seen yet
+	err = r.retryWrite("create", func() error {
+		_, err := r.client.Clientset.CoreV1().ConfigMaps(namespace).Create(configMap)
+		return err
+	})
+	recordWrite("create", "ConfigMap", err)
 	return err
 }
 
 func (r *Runner) UpdateConfigMap(namespace string, from v1.ConfigMap) (err error) {
-	log.Debugf("Updating ConfigMap %v in namespace %v", from.Name, namespace)
+	log.Debugf("Updating ConfigMap %v in namespace %v", copyName(&from), namespace)
 
-	configMap := createConfigMapObject(from)
-	configMap.ObjectMeta.Namespace = namespace
+	configMap, err := r.createConfigMapObject(namespace, from)
+	if err != nil {
+		return err
+	}
+
+	if r.dryRun {
+		var before map[string]interface{}
+		if live, err := r.client.Clientset.CoreV1().ConfigMaps(namespace).Get(configMap.Name, metav1.GetOptions{}); err == nil {
+			before = configMapContent(live)
+		}
+		r.planChange("update", "ConfigMap", namespace, &from, before, configMapContent(configMap))
+		return nil
+	}
 
-	_, err = r.client.Clientset.CoreV1().ConfigMaps(namespace).Update(configMap)
+	// updating the live object, a conflict fetches it again
+	err = r.retryWrite("update", func() error {
+		if r.serverSideApply {
+			return r.applyCore("configmaps", namespace, configMap.Name, configMap, &v1.ConfigMap{})
+		}
+		live, err := r.client.Clientset.CoreV1().ConfigMaps(namespace).Get(configMap.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		// keeping what other controllers added
+		updated := live.DeepCopy()
+		mergeMetadata(updated, configMap)
+		updated.Data = configMap.Data
+		updated.BinaryData = configMap.BinaryData
+		_, err = r.client.Clientset.CoreV1().ConfigMaps(namespace).Update(updated)
+		return err
+	})
+	recordWrite("update", "ConfigMap", err)
 	return err
 }
 
 func (r *Runner) DeleteConfigMap(namespace string, from v1.ConfigMap) (err error) {
-	log.Debugf("Removing ConfigMap %v from namespace %v", from.Name, namespace)
-	return r.client.Clientset.CoreV1().ConfigMaps(namespace).Delete(from.Name, &metav1.DeleteOptions{})
+	log.Debugf("Removing ConfigMap %v from namespace %v", copyName(&from), namespace)
+	if r.dryRun {
+		r.planChange("delete", "ConfigMap", namespace, &from, configMapContent(&from), nil)
+		return nil
+	}
+	err = r.retryWrite("delete", func() error {
+		return r.client.Clientset.CoreV1().ConfigMaps(namespace).Delete(copyName(&from), &metav1.DeleteOptions{})
+	})
+	recordWrite("delete", "ConfigMap", err)
+	return err
 }
 
-func createConfigMapObject(from v1.ConfigMap) *v1.ConfigMap {
-	labels := map[string]string{
-		"CreatedBy": "k8s-global-objects",
+// createConfigMapObject returns the copy of from for the namespace
+func (r *Runner) createConfigMapObject(namespace string, from v1.ConfigMap) (*v1.ConfigMap, error) {
+	data, err := r.renderValues(&from, namespace, publishedValues(&from, from.Data))
+	if err != nil {
+		return nil, err
+	}
+	data, err = r.overrideData(namespace, copyName(&from), data)
+	if err != nil {
+		return nil, err
 	}
-
 	return &v1.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ConfigMap",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   from.Name,
-			Labels: labels,
+			Name:        copyName(&from),
+			Namespace:   namespace,
+			Labels:      r.copyLabels(&from),
+			Annotations: r.copyAnnotations(&from),
 		},
-		Data: from.Data,
-	}
+		Data:       data,
+		BinaryData: publishedBytes(&from, from.BinaryData),
+	}, nil
 }
@@ -0,0 +1,129 @@
+package runner
+
+import (
+	"fmt"
+	"reflect"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Fields compared between a global object and its copies
+const (
+	compareData        = "data"
+	compareBinaryData  = "binaryData"
+	compareType        = "type"
+	compareLabels      = "labels"
+	compareAnnotations = "annotations"
+)
+
+// CompareFields lists every field drift detection can compare
+var CompareFields = []string{compareData, compareBinaryData, compareType, compareLabels, compareAnnotations}
+
+// contentFields are the fields that make up the content of an object, adopting compares them
+// whatever the configured fields are
+var contentFields = map[string]bool{compareData: true, compareBinaryData: true, compareType: true}
+
+// parseCompareFields turns the configured fields into a set, every field when none are configured
+func parseCompareFields(fields []string) (map[string]bool, error) {
+	if len(fields) == 0 {
+		fields = CompareFields
+	}
+	compare := make(map[string]bool)
+	for _, field := range fields {
+		known := false
+		for _, name := range CompareFields {
+			if field == name {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown compare field %q, expecting one of %v", field, CompareFields)
+		}
+		compare[field] = true
+	}
+	return compare, nil
+}
+
+// compareFieldList returns the compared fields in CompareFields order
+func (r *Runner) compareFieldList() []string {
+	fields := make([]string, 0, len(r.compareFields))
+	for _, field := range CompareFields {
+		if r.compareFields[field] {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// metadataDrift compares the labels and annotations the runner sets or propagated before, other controllers may add their own
+func metadataDrift(desired metav1.Object, existing metav1.Object, compare map[string]bool) []string {
+	drift := make([]string, 0)
+	staleLabels, staleAnnotations := stalePropagated(existing, desired)
+	if compare[compareLabels] && (len(staleLabels) > 0 || !containsAll(existing.GetLabels(), desired.GetLabels(), "")) {
+		drift = append(drift, compareLabels)
+	}
+	// the source resourceVersion changes with every write to the global object, the content comparison covers it
+	if compare[compareAnnotations] && (len(staleAnnotations) > 0 ||
+		!containsAll(existing.GetAnnotations(), desired.GetAnnotations(), sourceResourceVersionAnnotationKey)) {
+		drift = append(drift, compareAnnotations)
+	}
+	return drift
+}
+
+// containsAll reports if has holds every key of want with the same value, ignoring the skip key
+func containsAll(has map[string]string, want map[string]string, skip string) bool {
+	for key, value := range want {
+		if key == skip {
+			continue
+		}
+		if current, ok := has[key]; !ok || current != value {
+			return false
+		}
+	}
+	return true
+}
+
+// sameMap compares maps treating nil and empty as equal
+func sameMap(a interface{}, b interface{}) bool {
+	if reflect.ValueOf(a).Len() == 0 && reflect.ValueOf(b).Len() == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// configMapDrift returns the compared fields where the copy differs from the copy the runner would write
+func configMapDrift(desired *v1.ConfigMap, existing *v1.ConfigMap, compare map[string]bool) []string {
+	drift := make([]string, 0)
+	if compare[compareData] && !sameMap(desired.Data, existing.Data) {
+		drift = append(drift, compareData)
+	}
+	if compare[compareBinaryData] && !sameMap(desired.BinaryData, existing.BinaryData) {
+		drift = append(drift, compareBinaryData)
+	}
+	return append(drift, metadataDrift(desired, existing, compare)...)
+}
+
+// secretDrift returns the compared fields where the copy differs from the copy the runner would write
+func secretDrift(desired *v1.Secret, existing *v1.Secret, compare map[string]bool) []string {
+	drift := make([]string, 0)
+	if compare[compareData] && !sameMap(desired.Data, existing.Data) {
+		drift = append(drift, compareData)
+	}
+	if compare[compareType] && desired.Type != existing.Type {
+		drift = append(drift, compareType)
+	}
+	return append(drift, metadataDrift(desired, existing, compare)...)
+}
+
+// resourceDrift returns the compared fields where the copy differs from the copy the runner would write,
+// everything but the metadata of an additional resource counts as data
+func resourceDrift(desired *unstructured.Unstructured, existing *unstructured.Unstructured, compare map[string]bool) []string {
+	drift := make([]string, 0)
+	if compare[compareData] && !reflect.DeepEqual(resourceContent(*desired), resourceContent(*existing)) {
+		drift = append(drift, compareData)
+	}
+	return append(drift, metadataDrift(desired, existing, compare)...)
+}
@@ -0,0 +1,175 @@
+package runner_test
+
+import (
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// copyMeta is the metadata the runner puts on a copy of global
+func copyMeta(global metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:      global.Name,
+		Namespace: "default",
+		Labels:    map[string]string{"CreatedBy": "k8s-global-objects"},
+		Annotations: map[string]string{
+			"GlobalSourceNamespace":       global.Namespace,
+			"GlobalSourceName":            global.Name,
+			"GlobalSourceUID":             string(global.UID),
+			"GlobalSourceResourceVersion": global.ResourceVersion,
+		},
+	}
+}
+
+func countUpdates(client *runner.K8S, resource string) *int {
+	updates := 0
+	client.Clientset.(*fake.Clientset).Fake.PrependReactor("update", resource, func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		updates++
+		return false, nil, nil
+	})
+	return &updates
+}
+
+func TestDrift_ConfigMap_BinaryData(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{Name: "storeconfig-global", Namespace: "myapp", UID: "1234", ResourceVersion: "2"}
+	global.BinaryData = map[string][]byte{"logo.png": []byte("new")}
+
+	existing := v1.ConfigMap{
+		ObjectMeta: copyMeta(global.ObjectMeta),
+		Data:       global.Data,
+		BinaryData: map[string][]byte{"logo.png": []byte("old")},
+	}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("default").Create(&existing)
+	updates := countUpdates(config.Client, "configmaps")
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	configMapMaps := map[string]*runner.NamespaceConfigMaps{
+		"default": {Configmaps: []v1.ConfigMap{existing}},
+	}
+	err := runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	require.Equal(1, *updates)
+
+	res, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(global.BinaryData, res.BinaryData)
+
+	// an in sync copy is left alone, even when the global object resourceVersion moved on
+	global.ObjectMeta.ResourceVersion = "3"
+	configMapMaps["default"].Configmaps = []v1.ConfigMap{*res}
+	err = runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	require.Equal(1, *updates)
+}
+
+func TestDrift_Secret_Type(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+
+	global := secret
+	global.ObjectMeta = metav1.ObjectMeta{Name: "registry-global", Namespace: "myapp", UID: "1234"}
+	global.Type = v1.SecretTypeDockerConfigJson
+
+	existing := v1.Secret{
+		ObjectMeta: copyMeta(global.ObjectMeta),
+		Data:       global.Data,
+		Type:       v1.SecretTypeOpaque,
+	}
+	_, _ = config.Client.Clientset.CoreV1().Secrets("default").Create(&existing)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	secretMaps := map[string]*runner.NamepaceSecrets{
+		"default": {Secrets: []v1.Secret{existing}},
+	}
+	err := runr.AddAnnotatedSecret(secretMaps, "default", global)
+	require.NoError(err)
+
+	// the type can not be updated, the copy was recreated
+	res, err := config.Client.Clientset.CoreV1().Secrets("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(v1.SecretTypeDockerConfigJson, res.Type)
+	require.Equal(global.Data, res.Data)
+}
+
+func TestDrift_CompareFields(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.CompareFields = []string{"binaryData", "labels"}
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{Name: "storeconfig-global", Namespace: "myapp", UID: "1234"}
+
+	// data drifted, but data is not compared
+	existing := v1.ConfigMap{
+		ObjectMeta: copyMeta(global.ObjectMeta),
+		Data:       map[string]string{"changed": "by hand"},
+	}
+	existing.ObjectMeta.Labels["team"] = "payments"
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("default").Create(&existing)
+	updates := countUpdates(config.Client, "configmaps")
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	configMapMaps := map[string]*runner.NamespaceConfigMaps{
+		"default": {Configmaps: []v1.ConfigMap{existing}},
+	}
+	err := runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	require.Equal(0, *updates)
+
+	// labels the runner sets are compared
+	delete(existing.ObjectMeta.Labels, "CreatedBy")
+	config.ConflictPolicy = runner.ConflictOverwrite
+	runr = runner.NewRunner(&config)
+	defer runr.Close()
+
+	configMapMaps["default"].Configmaps = []v1.ConfigMap{existing}
+	err = runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	require.Equal(1, *updates)
+}
+
+func TestDrift_Init_BadCompareField(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.CompareFields = []string{"data", "status"}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Init()
+	require.Error(err)
+	require.Contains(err.Error(), "status")
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"fmt"
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DuplicatePolicy decides which global object is replicated when several namespaces publish the same name
+type DuplicatePolicy string
+
+const (
+	// DuplicateOldest replicates the oldest global object
+	DuplicateOldest DuplicatePolicy = "oldest"
+	// DuplicatePriority replicates the global object with the highest MakeGlobalPriority annotation, then the oldest
+	DuplicatePriority DuplicatePolicy = "priority"
+	// DuplicateNamespaces replicates the global object from the first listed source namespace, then the oldest
+	DuplicateNamespaces DuplicatePolicy = "namespaces"
+)
+
+func ParseDuplicatePolicy(value string) (DuplicatePolicy, error) {
+	switch policy := DuplicatePolicy(value); policy {
+	case DuplicateOldest, DuplicatePriority, DuplicateNamespaces:
+		return policy, nil
+	}
+	return "", fmt.Errorf("bad duplicate policy %q, expecting %v, %v or %v", value, DuplicateOldest, DuplicatePriority, DuplicateNamespaces)
+}
+
+const (
+	// Annotation ranking global objects sharing a name, the highest integer wins
+	priorityAnnotationKey = "MakeGlobalPriority"
+)
+
+// priority returns the MakeGlobalPriority of the object, 0 when missing or bad
+func priority(object metav1.Object) int {
+	value, ok := object.GetAnnotations()[priorityAnnotationKey]
+	if !ok {
+		return 0
+	}
+	p, err := strconv.Atoi(value)
+	if err != nil {
+		log.WithError(err).Warnf("bad %v annotation in %v - using 0", priorityAnnotationKey, object.GetSelfLink())
+		return 0
+	}
+	return p
+}
+
+// namespaceRank returns the position of the namespace in the source namespaces, unlisted namespaces come last
+func (r *Runner) namespaceRank(namespace string) int {
+	for i, name := range r.duplicateNamespaces {
+		if name == namespace {
+			return i
+		}
+	}
+	return len(r.duplicateNamespaces)
+}
+
+// preferred reports if a wins over b, every rule falls back to the oldest then the namespace name so the winner never flips
+func (r *Runner) preferred(a metav1.Object, b metav1.Object) bool {
+	switch r.duplicatePolicy {
+	case DuplicatePriority:
+		if pa, pb := priority(a), priority(b); pa != pb {
+			return pa > pb
+		}
+	case DuplicateNamespaces:
+		if ra, rb := r.namespaceRank(a.GetNamespace()), r.namespaceRank(b.GetNamespace()); ra != rb {
+			return ra < rb
+		}
+	}
+	ca, cb := a.GetCreationTimestamp(), b.GetCreationTimestamp()
+	if !ca.Equal(&cb) {
+		return ca.Before(&cb)
+	}
+	return a.GetNamespace() < b.GetNamespace()
+}
+
+// duplicateLosers returns the indexes of the global objects sharing their name with a preferred one
+func (r *Runner) duplicateLosers(kind string, objects []metav1.Object) map[int]bool {
+	// global objects collide when their copies get the same name
+	winners := make(map[string]int)
+	for i, object := range objects {
+		if winner, ok := winners[copyName(object)]; !ok || r.preferred(object, objects[winner]) {
+			winners[copyName(object)] = i
+		}
+	}
+
+	losers := make(map[int]bool)
+	for i, object := range objects {
+		winner := objects[winners[copyName(object)]]
+		if i == winners[copyName(object)] {
+			continue
+		}
+		losers[i] = true
+		log.Warnf("Duplicate: %v %v/%v is also published by %v/%v - replicating %v/%v (duplicate policy %v)",
+			kind, object.GetNamespace(), object.GetName(), winner.GetNamespace(), winner.GetName(),
+			winner.GetNamespace(), winner.GetName(), r.duplicatePolicy)
+		if loser, ok := object.(runtime.Object); ok {
+			r.event(loser, v1.EventTypeWarning, reasonDuplicate, "%v %v/%v is replicated instead, it takes precedence by the %v duplicate policy",
+				kind, winner.GetNamespace(), winner.GetName(), r.duplicatePolicy)
+		}
+	}
+	return losers
+}
+
+// duplicateKey identifies a global object losing to a same named one, no copies are written over it
+func duplicateKey(namespace string, name string) string {
+	return namespace + "/" + name
+}
+
+// dropDuplicates keeps one global object per name, returning the losers by duplicateKey
+func (r *Runner) dropDuplicates(k *syncKind, globals []metav1.Object) ([]metav1.Object, map[string]bool) {
+	if len(globals) == 0 {
+		return globals, nil
+	}
+	losers := r.duplicateLosers(k.kindOf(globals[0]), globals)
+
+	kept := make([]metav1.Object, 0, len(globals))
+	skipped := make(map[string]bool)
+	for i, global := range globals {
+		if losers[i] {
+			skipped[duplicateKey(global.GetNamespace(), global.GetName())] = true
+			// the status it got while replicated would stay forever
+			r.untrackSource(k.gvr, global)
+			continue
+		}
+		kept = append(kept, global)
+	}
+	return kept, skipped
+}
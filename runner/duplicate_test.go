@@ -0,0 +1,151 @@
+package runner_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// publishDuplicates creates a same named global ConfigMap in default and a newer one in myapp
+func publishDuplicates(client *runner.K8S, myappAnnotations map[string]string) (v1.ConfigMap, v1.ConfigMap) {
+	older := configmap
+	older.ObjectMeta = metav1.ObjectMeta{
+		Name:              "shared-global",
+		Namespace:         "default",
+		CreationTimestamp: metav1.NewTime(time.Date(2019, 1, 1, 0, 0, 0, 0, time.UTC)),
+		Annotations:       map[string]string{"MakeGlobal": "true"},
+	}
+	older.Data = map[string]string{"owner": "default"}
+	_, _ = client.Clientset.CoreV1().ConfigMaps("default").Create(&older)
+
+	newer := configmap
+	newer.ObjectMeta = metav1.ObjectMeta{
+		Name:              "shared-global",
+		Namespace:         "myapp",
+		CreationTimestamp: metav1.NewTime(time.Date(2019, 2, 1, 0, 0, 0, 0, time.UTC)),
+		Annotations:       map[string]string{"MakeGlobal": "true"},
+	}
+	for key, value := range myappAnnotations {
+		newer.ObjectMeta.Annotations[key] = value
+	}
+	newer.Data = map[string]string{"owner": "myapp"}
+	_, _ = client.Clientset.CoreV1().ConfigMaps("myapp").Create(&newer)
+
+	return older, newer
+}
+
+func TestDuplicate_Oldest(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	recorder := record.NewFakeRecorder(100)
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	config.Recorder = recorder
+	older, newer := publishDuplicates(config.Client, nil)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	copied, err := config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Get(older.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(older.Data, copied.Data)
+	require.Equal("default", copied.Annotations["GlobalSourceNamespace"])
+
+	// the losing global object is left alone
+	loser, err := config.Client.Clientset.CoreV1().ConfigMaps("myapp").Get(newer.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(newer.Data, loser.Data)
+
+	require.Contains(recordedEvents(recorder), "Warning DuplicateGlobalObject ConfigMap default/shared-global is replicated instead, it takes precedence by the oldest duplicate policy")
+}
+
+func TestDuplicate_Priority(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	config.DuplicatePolicy = runner.DuplicatePriority
+	_, newer := publishDuplicates(config.Client, map[string]string{"MakeGlobalPriority": "10"})
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	copied, err := config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Get(newer.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(newer.Data, copied.Data)
+}
+
+func TestDuplicate_Namespaces(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	config.DuplicatePolicy = runner.DuplicateNamespaces
+	config.DuplicateNamespaces = []string{"myapp"}
+	_, newer := publishDuplicates(config.Client, nil)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	copied, err := config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Get(newer.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(newer.Data, copied.Data)
+
+	// source namespaces are required
+	config.DuplicateNamespaces = nil
+	runr = runner.NewRunner(&config)
+	defer runr.Close()
+	require.Error(runr.Init())
+}
+
+func TestDuplicate_RemovedSameName(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	older, newer := publishDuplicates(config.Client, map[string]string{"MakeGlobal": "false"})
+
+	// the copy stays on every sync, the removed global object did not make it
+	for run := 0; run < 3; run++ {
+		runr := runner.NewRunner(&config)
+		require.NotNil(runr)
+
+		err := runr.Start()
+		require.NoError(err)
+		runr.Close()
+
+		copied, err := config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Get(older.Name, metav1.GetOptions{})
+		require.NoError(err, "run %v", run)
+		require.Equal(older.Data, copied.Data)
+	}
+
+	// the removed global object is left alone
+	_, err := config.Client.Clientset.CoreV1().ConfigMaps("myapp").Get(newer.Name, metav1.GetOptions{})
+	require.NoError(err)
+}
@@ -2,17 +2,25 @@ package runner
 
 import (
 	"errors"
-	"reflect"
+	"fmt"
 	"strconv"
 
 	log "github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 const (
 	// Annotation to track
 	annotationKey = "MakeGlobal"
+	// Annotation holding a label selector for the namespaces that receive the global object
+	namespaceSelectorAnnotationKey = "MakeGlobalNamespaceSelector"
+	// Namespace annotation to opt out of receiving global objects
+	excludeNamespaceAnnotationKey = "MakeGlobalExclude"
 )
 
 type NamespaceConfigMaps struct {
@@ -23,6 +31,10 @@ type NamepaceSecrets struct {
 	Secrets []v1.Secret
 }
 
+type NamespaceResources struct {
+	Objects []unstructured.Unstructured
+}
+
 func checkAnnotationKey(object metav1.Object) (string, error) {
 	if object == nil {
 		log.Debug("no object passed")
@@ -41,128 +53,183 @@ func checkAnnotationKey(object metav1.Object) (string, error) {
 	return annotatedGlobal, nil
 }
 
-func (r *Runner) AddAnnotatedConfigMap(configMapMaps map[string]*NamespaceConfigMaps, namespace string, globalConfigMap v1.ConfigMap) error {
-	// creating small map with objects for matching
-	myNamespaceConfigmaps := make(map[string]bool)
-	myNamespaceConfigmapObj := make(map[string]v1.ConfigMap)
-	for _, namespaceCM := range configMapMaps[namespace].Configmaps {
-		myNamespaceConfigmaps[namespaceCM.Name] = true
-		myNamespaceConfigmapObj[namespaceCM.Name] = namespaceCM
+func checkNamespaceSelector(object metav1.Object) (labels.Selector, error) {
+	if object == nil {
+		log.Debug("no object passed")
+		return nil, errors.New("no object passed")
+	}
+
+	chkSelector, ok := object.GetAnnotations()[namespaceSelectorAnnotationKey]
+	if !ok {
+		return labels.Everything(), nil
+	}
+	return labels.Parse(chkSelector)
+}
+
+func namespaceSelected(object metav1.Object, namespace v1.Namespace) bool {
+	selector, err := checkNamespaceSelector(object)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(labels.Set(namespace.Labels))
+}
+
+// checkGlobal validates the options of a global object to replicate, values are its templated data values
+func checkGlobal(object metav1.Object, values map[string]string) error {
+	if _, err := checkNamespaceSelector(object); err != nil {
+		return fmt.Errorf("bad %v annotation: %v", namespaceSelectorAnnotationKey, err)
+	}
+	if _, err := targetName(object); err != nil {
+		return fmt.Errorf("bad %v annotation: %v", nameTemplateAnnotationKey, err)
 	}
+	if err := checkTemplates(object, values); err != nil {
+		return fmt.Errorf("bad template: %v", err)
+	}
+	if err := checkKeyFilters(object); err != nil {
+		return fmt.Errorf("bad %v or %v annotation: %v", includeKeysAnnotationKey, excludeKeysAnnotationKey, err)
+	}
+	return nil
+}
+
+// addAnnotated creates or updates the copy of the global object in the namespace
+func (r *Runner) addAnnotated(k *syncKind, namespace string, global metav1.Object) error {
+	name := copyName(global)
+	kind := k.kindOf(global)
 
 	// check if the namespace have the the global object
-	if myNamespaceConfigmaps[globalConfigMap.Name] {
-		if !reflect.DeepEqual(globalConfigMap.Data, myNamespaceConfigmapObj[globalConfigMap.Name].Data) {
-			log.Infof("Detected drift in %v Overwriting it with %v", myNamespaceConfigmapObj[globalConfigMap.Name].SelfLink, globalConfigMap.SelfLink)
-			err := r.UpdateConfigMap(namespace, globalConfigMap)
+	if existing := k.object(namespace, name); existing != nil {
+		// comparing with the copy rendered for this namespace
+		desired, err := k.build(namespace, global)
+		if err != nil {
+			log.WithError(err).Errorf("Failed building %v %v for namespace %v", kind, name, namespace)
+			return err
+		}
+		drift := k.drift(desired, existing, r.compareFields)
+		// adopting needs the content to match, even when drift detection skips it
+		sameContent := len(k.drift(desired, existing, contentFields)) == 0
+		if !r.canWrite(existing, sameContent) {
+			r.reportConflict(existing, global)
+			return nil
+		}
+		if len(drift) > 0 || !isCopyOf(existing, global) {
+			log.Infof("Detected drift %v in %v Overwriting it with %v", drift, existing.GetSelfLink(), global.GetSelfLink())
+			driftDetected.WithLabelValues(kind).Inc()
+			err := k.update(namespace, global)
+			r.reportWrite(global, "update", kind, namespace, err)
 			if err != nil {
-				log.WithError(err).Errorf("Failed updating ConfigMap %v in namespace %v", globalConfigMap.Name, namespace)
+				log.WithError(err).Errorf("Failed updating %v %v in namespace %v", kind, name, namespace)
 				return err
 			}
+			if len(drift) > 0 {
+				r.reportOverwrite(existing, global, drift)
+			}
 			// updated the object - exit the function
 			return nil
 		}
-		// object exists and its identical - doing nothing - exit the function
+		// object exists and its identical - doing nothing
 		return nil
 	}
 
 	// object was not found so will create it
-	log.Debugf("Namespace %v missing global object %v", namespace, globalConfigMap.SelfLink)
-	log.Infof("Creating Global Object %v in namespace %v", globalConfigMap.SelfLink, namespace)
+	log.Debugf("Namespace %v missing global object %v", namespace, global.GetSelfLink())
+	log.Infof("Creating Global Object %v in namespace %v", global.GetSelfLink(), namespace)
 
-	err := r.CreateConfigMap(namespace, globalConfigMap)
+	err := k.create(namespace, global)
+	if apierrors.IsAlreadyExists(err) {
+		// informer cache has not caught up yet - next sync will compare it
+		log.Debugf("%v %v already exists in namespace %v", kind, name, namespace)
+		return nil
+	}
+	r.reportWrite(global, "create", kind, namespace, err)
 	if err != nil {
-		log.WithError(err).Errorf("Failed creating ConfigMap %v in namespace %v", globalConfigMap.Name, namespace)
+		log.WithError(err).Errorf("Failed creating %v %v in namespace %v", kind, name, namespace)
 		return err
 	}
-
 	return nil
 }
 
-func (r *Runner) RemoveAnnotatedConfigMap(configMapMaps map[string]*NamespaceConfigMaps, namespace string, globalConfigMap v1.ConfigMap) error {
-	// creating small map with objects for matching
-	myNamespaceConfigmaps := make(map[string]bool)
-	myNamespaceConfigmapObj := make(map[string]v1.ConfigMap)
-	for _, namespaceCM := range configMapMaps[namespace].Configmaps {
-		myNamespaceConfigmaps[namespaceCM.Name] = true
-		myNamespaceConfigmapObj[namespaceCM.Name] = namespaceCM
-	}
+// removeAnnotated deletes the copy of the global object from the namespace
+func (r *Runner) removeAnnotated(k *syncKind, namespace string, global metav1.Object) error {
+	name := copyName(global)
+	kind := k.kindOf(global)
 
 	// check if the namespace have the the global object that needs to be removed
-	if myNamespaceConfigmaps[globalConfigMap.Name] {
-		if reflect.DeepEqual(globalConfigMap.Name, myNamespaceConfigmapObj[globalConfigMap.Name].Name) {
-			// remove
-			log.Infof("Removing Global Object %v from namespace %v", globalConfigMap.SelfLink, namespace)
-			err := r.DeleteConfigMap(namespace, globalConfigMap)
-			if err != nil {
-				log.WithError(err).Errorf("Failed removing ConfigMap %v from namespace %v", globalConfigMap.Name, namespace)
-			}
-			return nil
-		}
+	existing := k.object(namespace, name)
+	if existing == nil {
 		return nil
 	}
-
-	return nil
-}
-
-func (r *Runner) AddAnnotatedSecret(secretMaps map[string]*NamepaceSecrets, namespace string, globalSecret v1.Secret) error {
-	// creating small map with objects for matching
-	myNamespaceSecrets := make(map[string]bool)
-	myNamespaceSecretObj := make(map[string]v1.Secret)
-	for _, namespaceSecret := range secretMaps[namespace].Secrets {
-		myNamespaceSecrets[namespaceSecret.Name] = true
-		myNamespaceSecretObj[namespaceSecret.Name] = namespaceSecret
+	if !r.canWrite(existing, false) {
+		r.reportConflict(existing, global)
+		return nil
 	}
-
-	// check if the namespace have the the global object
-	if myNamespaceSecrets[globalSecret.Name] {
-		if !reflect.DeepEqual(globalSecret.Data, myNamespaceSecretObj[globalSecret.Name].Data) {
-			log.Infof("Detected drift in %v Overwriting it with %v", myNamespaceSecretObj[globalSecret.Name].SelfLink, globalSecret.SelfLink)
-			err := r.UpdateSecret(namespace, globalSecret)
-			if err != nil {
-				log.WithError(err).Errorf("Failed updating Secret %v in namespace %v", globalSecret.Name, namespace)
-				return err
-			}
-			// updated the object - exit the function
-			return nil
-		}
-		// object exists and its identical - doing nothing
+	// copies of a same named global object stay, removing them would undo it on every sync,
+	// other objects are up to the conflict policy
+	if isCreatedByRunner(existing) && !isCopyOf(existing, global) {
+		log.Debugf("%v %v in namespace %v is not a copy of %v - keeping it", kind, name, namespace, global.GetSelfLink())
 		return nil
 	}
 
-	log.Debugf("Namespace %v missing global object %v", namespace, globalSecret.SelfLink)
-	log.Infof("Creating Global Object %v in namespace %v", globalSecret.SelfLink, namespace)
-
-	err := r.CreateSecret(namespace, globalSecret)
+	log.Infof("Removing Global Object %v from namespace %v", global.GetSelfLink(), namespace)
+	// the live object, so a dry run plans removing what the copy holds
+	err := k.delete(namespace, existing)
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	r.reportWrite(global, "delete", kind, namespace, err)
 	if err != nil {
-		log.WithError(err).Errorf("Failed creating Secret %v in namespace %v", globalSecret.Name, namespace)
+		log.WithError(err).Errorf("Failed removing %v %v from namespace %v", kind, name, namespace)
 		return err
 	}
 	return nil
 }
 
+func (r *Runner) AddAnnotatedConfigMap(configMapMaps map[string]*NamespaceConfigMaps, namespace string, globalConfigMap v1.ConfigMap) error {
+	return r.addAnnotated(r.configMapsOf(configMapMaps), namespace, &globalConfigMap)
+}
+
+func (r *Runner) RemoveAnnotatedConfigMap(configMapMaps map[string]*NamespaceConfigMaps, namespace string, globalConfigMap v1.ConfigMap) error {
+	return r.removeAnnotated(r.configMapsOf(configMapMaps), namespace, &globalConfigMap)
+}
+
+func (r *Runner) AddAnnotatedSecret(secretMaps map[string]*NamepaceSecrets, namespace string, globalSecret v1.Secret) error {
+	return r.addAnnotated(r.secretsOf(secretMaps), namespace, &globalSecret)
+}
+
 func (r *Runner) RemoveAnnotatedSecret(secretMaps map[string]*NamepaceSecrets, namespace string, globalSecret v1.Secret) error {
-	// creating small map with objects for matching
-	myNamespaceSecrets := make(map[string]bool)
-	myNamespaceSecretObj := make(map[string]v1.Secret)
-	for _, namespaceSecret := range secretMaps[namespace].Secrets {
-		myNamespaceSecrets[namespaceSecret.Name] = true
-		myNamespaceSecretObj[namespaceSecret.Name] = namespaceSecret
+	return r.removeAnnotated(r.secretsOf(secretMaps), namespace, &globalSecret)
+}
+
+func (r *Runner) AddAnnotatedResource(gvr schema.GroupVersionResource, resourceMaps map[string]*NamespaceResources, namespace string, globalObject unstructured.Unstructured) error {
+	return r.addAnnotated(r.resourcesOf(gvr, resourceMaps), namespace, &globalObject)
+}
+
+func (r *Runner) RemoveAnnotatedResource(gvr schema.GroupVersionResource, resourceMaps map[string]*NamespaceResources, namespace string, globalObject unstructured.Unstructured) error {
+	return r.removeAnnotated(r.resourcesOf(gvr, resourceMaps), namespace, &globalObject)
+}
+
+// configMapsOf returns the ConfigMap kind holding the listed ConfigMaps
+func (r *Runner) configMapsOf(configMapMaps map[string]*NamespaceConfigMaps) *syncKind {
+	k := r.configMapKind(nil)
+	for namespace, configMaps := range configMapMaps {
+		k.objects[namespace] = configMapObjects(configMaps.Configmaps)
 	}
+	return k
+}
 
-	// check if the namespace have the the global object
-	if myNamespaceSecrets[globalSecret.Name] {
-		if reflect.DeepEqual(globalSecret.Name, myNamespaceSecretObj[globalSecret.Name].Name) {
-			// remove
-			log.Infof("Removing Global Object %v from namespace %v", globalSecret.SelfLink, namespace)
-			err := r.DeleteSecret(namespace, globalSecret)
-			if err != nil {
-				log.WithError(err).Errorf("Failed removing Secret %v from namespace %v", globalSecret.Name, namespace)
-			}
-			return nil
-		}
-		return nil
+// secretsOf returns the Secret kind holding the listed Secrets
+func (r *Runner) secretsOf(secretMaps map[string]*NamepaceSecrets) *syncKind {
+	k := r.secretKind(nil)
+	for namespace, secrets := range secretMaps {
+		k.objects[namespace] = secretObjects(secrets.Secrets)
 	}
+	return k
+}
 
-	return nil
+// resourcesOf returns the kind of the additional resource holding the listed objects
+func (r *Runner) resourcesOf(gvr schema.GroupVersionResource, resourceMaps map[string]*NamespaceResources) *syncKind {
+	k := r.resourceKind(gvr)
+	for namespace, resources := range resourceMaps {
+		k.objects[namespace] = resourceObjects(resources.Objects)
+	}
+	return k
 }
@@ -10,7 +10,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/fake"
 	k8stesting "k8s.io/client-go/testing"
 )
@@ -173,6 +175,7 @@ func TestEngine_AddAnnotatedConfigMap_Update(t *testing.T) {
 	globalConfigMap := configmap
 	globalConfigMap.ObjectMeta.Name = "storeconfig-global"
 	globalConfigMap.ObjectMeta.SelfLink = "/made/up/path/configmap/" + "storeconfig-global"
+	globalConfigMap.ObjectMeta.Labels = map[string]string{"CreatedBy": "k8s-global-objects"}
 	globalConfigMap.Data = annotatedConfigMap.Data
 	namespaceConfigMaps = append(namespaceConfigMaps, globalConfigMap)
 	for _, tt := range k8s_client {
@@ -248,6 +251,7 @@ func TestEngine_AddAnnotatedConfigMap_Update_Fail(t *testing.T) {
 	globalConfigMap := configmap
 	globalConfigMap.ObjectMeta.Name = "storeconfig-global"
 	globalConfigMap.ObjectMeta.SelfLink = "/made/up/path/configmap/" + "storeconfig-global"
+	globalConfigMap.ObjectMeta.Labels = map[string]string{"CreatedBy": "k8s-global-objects"}
 	globalConfigMap.Data = annotatedConfigMap.Data
 	namespaceConfigMaps = append(namespaceConfigMaps, globalConfigMap)
 
@@ -327,7 +331,10 @@ func TestEngine_RemoveAnnotatedConfigMap(t *testing.T) {
 	_, err := config.Client.Clientset.CoreV1().ConfigMaps("myapp").Update(&annotatedConfigMap)
 	require.NoError(err)
 
-	namespaceConfigMaps = append(namespaceConfigMaps, annotatedConfigMap)
+	copiedConfigMap := annotatedConfigMap
+	copiedConfigMap.ObjectMeta.Labels = map[string]string{"CreatedBy": "k8s-global-objects"}
+	copiedConfigMap.ObjectMeta.Annotations = map[string]string{"GlobalSourceNamespace": "myapp", "GlobalSourceName": annotatedConfigMap.Name}
+	namespaceConfigMaps = append(namespaceConfigMaps, copiedConfigMap)
 	for _, tt := range k8s_client {
 		configMapMaps[tt.namespace] = &runner.NamespaceConfigMaps{Configmaps: namespaceConfigMaps}
 	}
@@ -515,6 +522,7 @@ func TestEngine_AddAnnotatedSecret_Update(t *testing.T) {
 	globalSecret := secret
 	globalSecret.ObjectMeta.Name = "mykey-global"
 	globalSecret.ObjectMeta.SelfLink = "/made/up/path/secret/" + "mykey-global"
+	globalSecret.ObjectMeta.Labels = map[string]string{"CreatedBy": "k8s-global-objects"}
 	globalSecret.Data = annotatedSecret.Data
 	namespaceSecret = append(namespaceSecret, globalSecret)
 	for _, tt := range k8s_client {
@@ -590,6 +598,7 @@ func TestEngine_AddAnnotatedSecret_Update_Fail(t *testing.T) {
 	globalSecret := secret
 	globalSecret.ObjectMeta.Name = "mykey-global"
 	globalSecret.ObjectMeta.SelfLink = "/made/up/path/secret/" + "mykey-global"
+	globalSecret.ObjectMeta.Labels = map[string]string{"CreatedBy": "k8s-global-objects"}
 	globalSecret.Data = annotatedSecret.Data
 	namespaceSecret = append(namespaceSecret, globalSecret)
 
@@ -670,7 +679,10 @@ func TestEngine_RemoveAnnotatedSecret(t *testing.T) {
 	_, err := config.Client.Clientset.CoreV1().Secrets("myapp").Update(&annotatedSecret)
 	require.NoError(err)
 
-	namespaceSecret = append(namespaceSecret, annotatedSecret)
+	copiedSecret := annotatedSecret
+	copiedSecret.ObjectMeta.Labels = map[string]string{"CreatedBy": "k8s-global-objects"}
+	copiedSecret.ObjectMeta.Annotations = map[string]string{"GlobalSourceNamespace": "myapp", "GlobalSourceName": annotatedSecret.Name}
+	namespaceSecret = append(namespaceSecret, copiedSecret)
 	for _, tt := range k8s_client {
 		secretMap[tt.namespace] = &runner.NamepaceSecrets{Secrets: namespaceSecret}
 	}
@@ -697,3 +709,174 @@ func TestEngine_RemoveAnnotatedSecret(t *testing.T) {
 	require.NotEmpty(res)
 	require.Equal(res, &annotatedSecret)
 }
+
+// resources
+func TestEngine_AddAnnotatedResource(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_dynamic_client(fake_simple_client())
+	config.Debug = true
+	config.Once = true
+	config.RunInterval = 1 * time.Millisecond
+
+	globalObject := limitRange("myapp", "limits-global", "1")
+	globalObject.SetAnnotations(map[string]string{"MakeGlobal": "true"})
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	resourceMaps := make(map[string]*runner.NamespaceResources)
+	for _, tt := range k8s_client {
+		resourceMaps[tt.namespace] = &runner.NamespaceResources{Objects: []unstructured.Unstructured{}}
+	}
+	// drifted copy in default
+	resourceMaps["default"].Objects = append(resourceMaps["default"].Objects, *limitRangeCopy("default", "limits-global", "5"))
+	_, err := config.Client.Dynamic.Resource(limitRangeResource).Namespace("default").Create(limitRangeCopy("default", "limits-global", "5"), metav1.CreateOptions{})
+	require.NoError(err)
+
+	for _, tt := range k8s_client {
+		if globalObject.GetNamespace() == tt.namespace {
+			continue
+		}
+		err := runr.AddAnnotatedResource(limitRangeResource, resourceMaps, tt.namespace, *globalObject)
+		require.NoError(err)
+
+		res, err := config.Client.Dynamic.Resource(limitRangeResource).Namespace(tt.namespace).Get(globalObject.GetName(), metav1.GetOptions{})
+		require.NoError(err)
+		require.Equal(globalObject.Object["spec"], res.Object["spec"])
+	}
+}
+
+func TestEngine_AddAnnotatedResource_Fail(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_dynamic_client(fake_simple_client())
+	config.Client.Dynamic.(*dynamicfake.FakeDynamicClient).PrependReactor("create", "limitranges", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, errors.New("you no create limitrange")
+	})
+	config.Debug = true
+
+	globalObject := limitRange("myapp", "limits-global", "1")
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	resourceMaps := map[string]*runner.NamespaceResources{
+		"default": {Objects: []unstructured.Unstructured{}},
+	}
+	err := runr.AddAnnotatedResource(limitRangeResource, resourceMaps, "default", *globalObject)
+	require.Error(err)
+}
+
+func TestEngine_RemoveAnnotatedResource(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_dynamic_client(fake_simple_client(), limitRangeCopy("default", "limits-global", "1"))
+	config.Debug = true
+
+	globalObject := limitRange("myapp", "limits-global", "1")
+	globalObject.SetAnnotations(map[string]string{"MakeGlobal": "false"})
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	resourceMaps := map[string]*runner.NamespaceResources{
+		"default": {Objects: []unstructured.Unstructured{*limitRangeCopy("default", "limits-global", "1")}},
+	}
+	err := runr.RemoveAnnotatedResource(limitRangeResource, resourceMaps, "default", *globalObject)
+	require.NoError(err)
+
+	_, err = config.Client.Dynamic.Resource(limitRangeResource).Namespace("default").Get(globalObject.GetName(), metav1.GetOptions{})
+	require.Error(err)
+}
+
+// conflicts
+func TestEngine_ConflictPolicy(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	globalConfigMap := configmap
+	globalConfigMap.ObjectMeta.Name = "storeconfig-global"
+	globalConfigMap.ObjectMeta.SelfLink = "/made/up/path/configmap/" + "storeconfig-global"
+	globalConfigMap.ObjectMeta.Namespace = "myapp"
+	globalConfigMap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	globalConfigMap.Data = map[string]string{"KEY": "VALUE"}
+
+	tests := []struct {
+		policy      runner.ConflictPolicy
+		compare     []string
+		userData    map[string]string
+		expectData  map[string]string
+		expectOwned bool
+		expectGone  bool
+	}{
+		{policy: runner.ConflictSkip, userData: map[string]string{"KEY": "MINE"}, expectData: map[string]string{"KEY": "MINE"}},
+		{policy: runner.ConflictSkip, userData: map[string]string{"KEY": "VALUE"}, expectData: map[string]string{"KEY": "VALUE"}},
+		{policy: runner.ConflictAdopt, userData: map[string]string{"KEY": "MINE"}, expectData: map[string]string{"KEY": "MINE"}},
+		{policy: runner.ConflictAdopt, userData: map[string]string{"KEY": "VALUE"}, expectData: map[string]string{"KEY": "VALUE"}, expectOwned: true},
+		// the content is compared for adopting even when drift detection skips it
+		{policy: runner.ConflictAdopt, compare: []string{"labels"}, userData: map[string]string{"KEY": "MINE"}, expectData: map[string]string{"KEY": "MINE"}},
+		{policy: runner.ConflictAdopt, compare: []string{"labels"}, userData: map[string]string{"KEY": "VALUE"}, expectData: map[string]string{"KEY": "VALUE"}, expectOwned: true},
+		{policy: runner.ConflictOverwrite, userData: map[string]string{"KEY": "MINE"}, expectData: map[string]string{"KEY": "VALUE"}, expectOwned: true, expectGone: true},
+	}
+
+	for _, tt := range tests {
+		config := *runner.DefaultConfig()
+		config.Client = fake_simple_client()
+		config.Debug = true
+		config.ConflictPolicy = tt.policy
+		config.CompareFields = tt.compare
+
+		// hand written configmap with the same name
+		userConfigMap := configmap
+		userConfigMap.ObjectMeta.Name = globalConfigMap.Name
+		userConfigMap.ObjectMeta.Namespace = "default"
+		userConfigMap.Data = tt.userData
+		_, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Create(&userConfigMap)
+		require.NoError(err)
+
+		runr := runner.NewRunner(&config)
+		require.NotNil(runr)
+
+		configMapMaps := map[string]*runner.NamespaceConfigMaps{
+			"default": {Configmaps: []v1.ConfigMap{userConfigMap}},
+		}
+		err = runr.AddAnnotatedConfigMap(configMapMaps, "default", globalConfigMap)
+		require.NoError(err)
+
+		res, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(globalConfigMap.Name, metav1.GetOptions{})
+		require.NoError(err)
+		require.Equal(tt.expectData, res.Data, string(tt.policy))
+		require.Equal(tt.expectOwned, res.Labels["CreatedBy"] == "k8s-global-objects", string(tt.policy))
+
+		// removal of the hand written configmap
+		err = runr.RemoveAnnotatedConfigMap(configMapMaps, "default", globalConfigMap)
+		require.NoError(err)
+
+		_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get(globalConfigMap.Name, metav1.GetOptions{})
+		require.Equal(tt.expectGone, err != nil, string(tt.policy))
+		runr.Close()
+	}
+}
+
+func TestEngine_ParseConflictPolicy(t *testing.T) {
+	require := require.New(t)
+
+	for _, value := range []string{"skip", "adopt", "overwrite"} {
+		policy, err := runner.ParseConflictPolicy(value)
+		require.NoError(err)
+		require.Equal(runner.ConflictPolicy(value), policy)
+	}
+
+	_, err := runner.ParseConflictPolicy("clobber")
+	require.Error(err)
+}
@@ -0,0 +1,85 @@
+package runner
+
+import (
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// eventSource is the component name events are reported by
+const eventSource = "k8s-global-objects"
+
+// Event reasons
+const (
+	reasonCreated          = "CopyCreated"
+	reasonUpdated          = "CopyUpdated"
+	reasonDeleted          = "CopyDeleted"
+	reasonFailed           = "CopyFailed"
+	reasonConflict         = "CopyConflict"
+	reasonDriftOverwritten = "DriftOverwritten"
+	reasonDuplicate        = "DuplicateGlobalObject"
+	reasonSourceNotAllowed = "SourceNamespaceNotAllowed"
+)
+
+// writeEvents maps the write verbs to the event they record on success
+var writeEvents = map[string]struct{ reason, done string }{
+	"create": {reasonCreated, "created in"},
+	"update": {reasonUpdated, "updated in"},
+	"delete": {reasonDeleted, "deleted from"},
+}
+
+// startRecording records events through the API server unless a recorder was configured
+func (r *Runner) startRecording() {
+	if r.recorder != nil {
+		return
+	}
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartLogging(log.Debugf)
+	r.eventWatch = broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: r.client.Clientset.CoreV1().Events("")})
+	r.recorder = broadcaster.NewRecorder(scheme.Scheme, v1.EventSource{Component: eventSource})
+}
+
+// stopRecording stops sending events to the API server
+func (r *Runner) stopRecording() {
+	if r.eventWatch != nil {
+		r.eventWatch.Stop()
+	}
+}
+
+// event records an event on object, dry runs do not record any
+func (r *Runner) event(object runtime.Object, eventType string, reason string, messageFmt string, args ...interface{}) {
+	if r.recorder == nil || r.dryRun {
+		return
+	}
+	// global objects of source providers are not in the cluster
+	if accessor, err := meta.Accessor(object); err == nil && r.externalSource(accessor.GetNamespace()) {
+		return
+	}
+	r.recorder.Eventf(object, eventType, reason, messageFmt, args...)
+}
+
+// reportWrite records the outcome of writing a copy of the global object in a target namespace on the global object
+func (r *Runner) reportWrite(global metav1.Object, verb string, kind string, namespace string, err error) {
+	object, ok := global.(runtime.Object)
+	if !ok {
+		return
+	}
+	if err != nil {
+		r.event(object, v1.EventTypeWarning, reasonFailed, "Failed to %v %v copy in namespace %v: %v", verb, kind, namespace, err)
+		return
+	}
+	r.event(object, v1.EventTypeNormal, writeEvents[verb].reason, "%v copy %v namespace %v", kind, writeEvents[verb].done, namespace)
+}
+
+// reportOverwrite records on a drifted copy that the runner overwrote it
+func (r *Runner) reportOverwrite(copied metav1.Object, global metav1.Object, drift []string) {
+	if object, ok := copied.(runtime.Object); ok {
+		r.event(object, v1.EventTypeNormal, reasonDriftOverwritten, "Drifted %v overwritten with global object %v/%v",
+			drift, global.GetNamespace(), global.GetName())
+	}
+}
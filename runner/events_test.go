@@ -0,0 +1,124 @@
+package runner_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/record"
+)
+
+// recordedEvents drains the events recorded so far
+func recordedEvents(recorder *record.FakeRecorder) []string {
+	events := make([]string, 0)
+	for {
+		select {
+		case event := <-recorder.Events:
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func TestEvents_ConfigMap(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	recorder := record.NewFakeRecorder(10)
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Recorder = recorder
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{Name: "storeconfig-global", Namespace: "myapp", UID: "1234"}
+	configMapMaps := map[string]*runner.NamespaceConfigMaps{
+		"default": {Configmaps: []v1.ConfigMap{}},
+	}
+
+	err := runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	require.Equal("Normal CopyCreated ConfigMap copy created in namespace default", <-recorder.Events)
+
+	// the drifted copy is told why it changed
+	existing := v1.ConfigMap{ObjectMeta: copyMeta(global.ObjectMeta), Data: map[string]string{"changed": "by hand"}}
+	configMapMaps["default"].Configmaps = []v1.ConfigMap{existing}
+	err = runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	require.Equal("Normal CopyUpdated ConfigMap copy updated in namespace default", <-recorder.Events)
+	require.Equal("Normal DriftOverwritten Drifted [data] overwritten with global object myapp/storeconfig-global", <-recorder.Events)
+
+	err = runr.RemoveAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	require.Equal("Normal CopyDeleted ConfigMap copy deleted from namespace default", <-recorder.Events)
+
+	// someone elses object
+	existing.ObjectMeta.Labels = nil
+	configMapMaps["default"].Configmaps = []v1.ConfigMap{existing}
+	err = runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	require.Contains(<-recorder.Events, "Warning CopyConflict default/storeconfig-global was not created by k8s-global-objects")
+	require.Empty(recorder.Events)
+}
+
+func TestEvents_Failure(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	recorder := record.NewFakeRecorder(10)
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Recorder = recorder
+	config.MaxRetries = 0
+	config.Client.Clientset.(*fake.Clientset).Fake.PrependReactor("create", "secrets", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, errors.New("admission denied")
+	})
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	global := secret
+	global.ObjectMeta = metav1.ObjectMeta{Name: "registry-global", Namespace: "myapp"}
+	secretMaps := map[string]*runner.NamepaceSecrets{
+		"default": {Secrets: []v1.Secret{}},
+	}
+	err := runr.AddAnnotatedSecret(secretMaps, "default", global)
+	require.Error(err)
+	require.Equal("Warning CopyFailed Failed to create Secret copy in namespace default: admission denied", <-recorder.Events)
+}
+
+func TestEvents_DryRun(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	recorder := record.NewFakeRecorder(10)
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Recorder = recorder
+	config.DryRun = true
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{Name: "storeconfig-global", Namespace: "myapp"}
+	configMapMaps := map[string]*runner.NamespaceConfigMaps{
+		"default": {Configmaps: []v1.ConfigMap{}},
+	}
+	err := runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	require.Empty(recorder.Events)
+}
@@ -5,6 +5,10 @@ import (
 	"k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
 	"k8s.io/client-go/kubernetes/fake"
 )
 
@@ -43,6 +47,29 @@ var secret = v1.Secret{
 	},
 }
 
+var limitRangeResource = schema.GroupVersionResource{Version: "v1", Resource: "limitranges"}
+
+func limitRange(namespace string, name string, max string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "LimitRange",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+			"spec": map[string]interface{}{
+				"limits": []interface{}{
+					map[string]interface{}{
+						"type": "Container",
+						"max":  map[string]interface{}{"cpu": max},
+					},
+				},
+			},
+		},
+	}
+}
+
 var k8s_client = []struct {
 	namespace string
 }{
@@ -56,9 +83,9 @@ var policyRules = []struct {
 	resources []string
 	verbs     []string
 }{
-	{apiGroup: []string{""}, resources: []string{"configmaps"}, verbs: []string{"get", "list", "create", "update", "delete"}},
-	{apiGroup: []string{""}, resources: []string{"secrets"}, verbs: []string{"get", "list", "create", "update", "delete"}},
-	{apiGroup: []string{""}, resources: []string{"namespaces"}, verbs: []string{"list"}},
+	{apiGroup: []string{""}, resources: []string{"configmaps"}, verbs: []string{"get", "list", "watch", "create", "update", "delete"}},
+	{apiGroup: []string{""}, resources: []string{"secrets"}, verbs: []string{"get", "list", "watch", "create", "update", "delete"}},
+	{apiGroup: []string{""}, resources: []string{"namespaces"}, verbs: []string{"list", "watch"}},
 }
 
 var clusterRoleRules = []rbacv1.PolicyRule{}
@@ -115,11 +142,7 @@ func fake_simple_client() *runner.K8S {
 
 	// create service account
 	_, _ = client.Clientset.CoreV1().ServiceAccounts(appNamespace).Create(&v1.ServiceAccount{
-		metav1.TypeMeta{},
-		metav1.ObjectMeta{Name: appNamespace},
-		nil,
-		nil,
-		nil,
+		ObjectMeta: metav1.ObjectMeta{Name: appNamespace},
 	})
 
 	// create rules
@@ -127,29 +150,39 @@ func fake_simple_client() *runner.K8S {
 
 	// create cluster role
 	_, _ = client.Clientset.RbacV1().ClusterRoles().Create(&rbacv1.ClusterRole{
-		metav1.TypeMeta{},
-		metav1.ObjectMeta{Name: svcAccount},
-		clusterRoleRules,
-		nil,
+		ObjectMeta: metav1.ObjectMeta{Name: svcAccount},
+		Rules:      clusterRoleRules,
 	})
 
 	// create cluste role bindings
 	_, _ = client.Clientset.RbacV1().ClusterRoleBindings().Create(&rbacv1.ClusterRoleBinding{
-		metav1.TypeMeta{},
-		metav1.ObjectMeta{Name: clusterRoleBinding},
-		[]rbacv1.Subject{
-			rbacv1.Subject{
+		ObjectMeta: metav1.ObjectMeta{Name: clusterRoleBinding},
+		Subjects: []rbacv1.Subject{
+			{
 				Kind:      "ServiceAccount",
 				Name:      svcAccount,
 				Namespace: appNamespace,
 			},
 		},
-		rbacv1.RoleRef{
-			"rbac.authorization.k8s.io",
-			"ClusterRole",
-			roleRef,
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: "rbac.authorization.k8s.io",
+			Kind:     "ClusterRole",
+			Name:     roleRef,
 		},
 	})
 
 	return client
 }
+
+func fake_dynamic_client(client *runner.K8S, objects ...runtime.Object) *runner.K8S {
+	client.Dynamic = dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objects...)
+	return client
+}
+
+// limitRangeCopy returns a copy of the myapp global object of the same name
+func limitRangeCopy(namespace string, name string, max string) *unstructured.Unstructured {
+	object := limitRange(namespace, name, max)
+	object.SetLabels(map[string]string{"CreatedBy": "k8s-global-objects"})
+	object.SetAnnotations(map[string]string{"GlobalSourceNamespace": "myapp", "GlobalSourceName": name})
+	return object
+}
@@ -0,0 +1,99 @@
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// wedgedIntervals is how many run intervals the loop may go without finishing a sync before it is considered wedged
+const wedgedIntervals = 3
+
+// markAccessValidated records that Init confirmed the runner permissions
+func (r *Runner) markAccessValidated() {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+	r.accessValidated = true
+}
+
+// markLoopRunning records that the sync loop is alive, running is false once it exited
+func (r *Runner) markLoopRunning(running bool) {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+	r.loopRunning = running
+	r.lastHeartbeat = time.Now()
+}
+
+// markStandby records if the runner waits for the leader election lease
+func (r *Runner) markStandby(standby bool) {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+	r.standby = standby
+}
+
+// markSynced records a finished sync, a sync that failed in some namespaces still completed
+func (r *Runner) markSynced(err error) {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+	r.lastHeartbeat = time.Now()
+	if err == nil {
+		r.lastSuccessfulSync = r.lastHeartbeat
+	}
+}
+
+// Healthy reports an error when the sync loop exited or has not finished a sync for too long
+func (r *Runner) Healthy() error {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+
+	// not started yet, still initializing
+	if r.lastHeartbeat.IsZero() {
+		return nil
+	}
+	if !r.loopRunning {
+		return errors.New("sync loop exited")
+	}
+	if since := time.Since(r.lastHeartbeat); since > wedgedIntervals*r.runInterval {
+		return fmt.Errorf("no sync finished for %v", since.Round(time.Second))
+	}
+	return nil
+}
+
+// Ready reports an error until access was validated and a sync finished successfully,
+// standby replicas are ready once access was validated
+func (r *Runner) Ready() error {
+	r.healthLock.Lock()
+	defer r.healthLock.Unlock()
+
+	if !r.accessValidated {
+		return errors.New("access not validated")
+	}
+	if r.standby {
+		return nil
+	}
+	if r.lastSuccessfulSync.IsZero() {
+		return errors.New("no successful sync yet")
+	}
+	return nil
+}
+
+// HealthzHandler serves the liveness probe
+func (r *Runner) HealthzHandler() http.Handler {
+	return probeHandler(r.Healthy)
+}
+
+// ReadyzHandler serves the readiness probe
+func (r *Runner) ReadyzHandler() http.Handler {
+	return probeHandler(r.Ready)
+}
+
+func probeHandler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if err := check(); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+}
@@ -0,0 +1,74 @@
+package runner
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth_Healthy(t *testing.T) {
+	require := require.New(t)
+
+	config := *DefaultConfig()
+	config.RunInterval = time.Minute
+	r := NewRunner(&config)
+
+	// still initializing
+	require.NoError(r.Healthy())
+
+	r.markLoopRunning(true)
+	require.NoError(r.Healthy())
+
+	r.markSynced(errors.New("sync failed"))
+	require.NoError(r.Healthy())
+
+	// no sync finished for too long
+	r.lastHeartbeat = time.Now().Add(-wedgedIntervals*config.RunInterval - time.Second)
+	require.Error(r.Healthy())
+
+	r.markSynced(nil)
+	require.NoError(r.Healthy())
+
+	r.markLoopRunning(false)
+	require.EqualError(r.Healthy(), "sync loop exited")
+}
+
+func TestHealth_Ready(t *testing.T) {
+	require := require.New(t)
+
+	r := NewRunner(DefaultConfig())
+	require.EqualError(r.Ready(), "access not validated")
+
+	r.markAccessValidated()
+	require.EqualError(r.Ready(), "no successful sync yet")
+
+	r.markSynced(errors.New("sync failed"))
+	require.Error(r.Ready())
+
+	r.markSynced(nil)
+	require.NoError(r.Ready())
+}
+
+func TestHealth_Handlers(t *testing.T) {
+	require := require.New(t)
+
+	r := NewRunner(DefaultConfig())
+
+	recorder := httptest.NewRecorder()
+	r.HealthzHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/healthz", nil))
+	require.Equal(200, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	r.ReadyzHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	require.Equal(503, recorder.Code)
+	require.Contains(recorder.Body.String(), "access not validated")
+
+	r.markAccessValidated()
+	r.markSynced(nil)
+	recorder = httptest.NewRecorder()
+	r.ReadyzHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/readyz", nil))
+	require.Equal(200, recorder.Code)
+}
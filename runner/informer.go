@@ -0,0 +1,189 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// Key used to queue a reconcile of every global object
+	syncKey = "sync"
+)
+
+func (r *Runner) setupInformers() {
+	log.Debug("Setting up Namespace, ConfigMap and Secret informers")
+
+	// resync is driven by the runner ticker, not by the informers
+	r.informerFactory = informers.NewSharedInformerFactory(r.client.Clientset, 0)
+	r.queue = workqueue.NewNamedRateLimitingQueue(r.syncRateLimiter(), "k8s-global-objects")
+
+	handler := cache.ResourceEventHandlerFuncs{
+		AddFunc:    r.onAdd,
+		UpdateFunc: r.onUpdate,
+		DeleteFunc: r.onDelete,
+	}
+
+	namespaceInformer := r.informerFactory.Core().V1().Namespaces()
+	namespaceInformer.Informer().AddEventHandler(handler)
+	r.namespaceLister = namespaceInformer.Lister()
+
+	configMapInformer := r.informerFactory.Core().V1().ConfigMaps()
+	configMapInformer.Informer().AddEventHandler(handler)
+	r.configMapLister = configMapInformer.Lister()
+
+	secretInformer := r.informerFactory.Core().V1().Secrets()
+	secretInformer.Informer().AddEventHandler(handler)
+	r.secretLister = secretInformer.Lister()
+
+	r.informersSynced = []cache.InformerSynced{
+		namespaceInformer.Informer().HasSynced,
+		configMapInformer.Informer().HasSynced,
+		secretInformer.Informer().HasSynced,
+	}
+
+	if len(r.resources) == 0 {
+		return
+	}
+
+	log.Debugf("Setting up informers for %v additional resources", len(r.resources))
+	r.dynamicInformerFactory = dynamicinformer.NewDynamicSharedInformerFactory(r.client.Dynamic, 0)
+	r.resourceListers = make(map[schema.GroupVersionResource]cache.GenericLister)
+	for _, gvr := range r.resources {
+		resourceInformer := r.dynamicInformerFactory.ForResource(gvr)
+		resourceInformer.Informer().AddEventHandler(handler)
+		r.resourceListers[gvr] = resourceInformer.Lister()
+		r.informersSynced = append(r.informersSynced, resourceInformer.Informer().HasSynced)
+	}
+}
+
+// shutdownQueue stops the queue once, the delaying queue panics when shut down twice
+func (r *Runner) shutdownQueue() {
+	r.queueShutdown.Do(r.queue.ShutDown)
+}
+
+func (r *Runner) enqueueSync() {
+	r.queue.Add(syncKey)
+}
+
+// relevantObject reports if a change to the object can change what the runner writes,
+// busy clusters change unrelated ConfigMaps all the time
+func relevantObject(obj interface{}) bool {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	// labels and annotations of namespaces select and exclude them
+	if _, ok := obj.(*v1.Namespace); ok {
+		return true
+	}
+	object, ok := obj.(metav1.Object)
+	if !ok {
+		return true
+	}
+	if _, ok := object.GetAnnotations()[annotationKey]; ok {
+		return true
+	}
+	return isCreatedByRunner(object) || isOverride(object)
+}
+
+func (r *Runner) onAdd(obj interface{}) {
+	if !relevantObject(obj) {
+		return
+	}
+	r.enqueueSync()
+}
+
+func (r *Runner) onUpdate(oldObj, newObj interface{}) {
+	oldMeta, okOld := oldObj.(metav1.Object)
+	newMeta, okNew := newObj.(metav1.Object)
+	// nothing changed - skipping
+	if okOld && okNew && oldMeta.GetResourceVersion() == newMeta.GetResourceVersion() {
+		return
+	}
+	// the runner writing the status of a global object - skipping
+	if statusOnlyUpdate(oldObj, newObj) {
+		return
+	}
+	// the old object counts too, an object can stop being global or a copy
+	if !relevantObject(oldObj) && !relevantObject(newObj) {
+		return
+	}
+	r.enqueueSync()
+}
+
+func (r *Runner) onDelete(obj interface{}) {
+	if !relevantObject(obj) {
+		return
+	}
+	r.enqueueSync()
+}
+
+func (r *Runner) cachedNamespaces() ([]v1.Namespace, error) {
+	namespaces, err := r.namespaceLister.List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]v1.Namespace, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		result = append(result, *namespace)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (r *Runner) cachedConfigMaps(namespace string) ([]v1.ConfigMap, error) {
+	configmaps, err := r.configMapLister.ConfigMaps(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]v1.ConfigMap, 0, len(configmaps))
+	for _, configmap := range configmaps {
+		result = append(result, *configmap)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (r *Runner) cachedSecrets(namespace string) ([]v1.Secret, error) {
+	secrets, err := r.secretLister.Secrets(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]v1.Secret, 0, len(secrets))
+	for _, secret := range secrets {
+		result = append(result, *secret)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (r *Runner) cachedResources(gvr schema.GroupVersionResource, namespace string) ([]unstructured.Unstructured, error) {
+	objects, err := r.resourceListers[gvr].ByNamespace(namespace).List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]unstructured.Unstructured, 0, len(objects))
+	for _, object := range objects {
+		unstructuredObject, ok := object.(*unstructured.Unstructured)
+		if !ok {
+			return nil, fmt.Errorf("unexpected object type %T in %v cache", object, gvr.Resource)
+		}
+		result = append(result, *unstructuredObject)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].GetName() < result[j].GetName() })
+	return result, nil
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestInformer_statusOnlyUpdate(t *testing.T) {
+	require := require.New(t)
+
+	old := &v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "storeconfig-global",
+			ResourceVersion: "1",
+			Annotations:     map[string]string{annotationKey: "true"},
+		},
+		Data: map[string]string{"some": "data"},
+	}
+
+	// the runner writing the status does not queue another sync
+	status := old.DeepCopy()
+	status.ResourceVersion = "2"
+	status.Annotations[syncStatusAnnotationKey] = `{"lastSync":"2019-01-22T23:20:22Z"}`
+	require.True(statusOnlyUpdate(old, status))
+
+	changed := status.DeepCopy()
+	changed.ResourceVersion = "3"
+	changed.Data["some"] = "other"
+	require.False(statusOnlyUpdate(status, changed))
+
+	// the original object was not touched
+	require.Len(old.Annotations, 1)
+}
+
+func TestInformer_relevantObject(t *testing.T) {
+	require := require.New(t)
+
+	require.True(relevantObject(&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}))
+	require.True(relevantObject(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{annotationKey: "false"}}}))
+	require.True(relevantObject(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: ownershipLabels()}}))
+	require.True(relevantObject(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{overrideAnnotationKey: "true"}}}))
+	require.True(relevantObject(cache.DeletedFinalStateUnknown{Obj: &v1.Secret{ObjectMeta: metav1.ObjectMeta{Labels: ownershipLabels()}}}))
+
+	// leader election and other busy ConfigMaps do not queue syncs
+	require.False(relevantObject(&v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "leader", Annotations: map[string]string{"control-plane.alpha.kubernetes.io/leader": "{}"}}}))
+	require.False(relevantObject(cache.DeletedFinalStateUnknown{Obj: &v1.Secret{}}))
+}
+
+func TestInformer_requeueFailed(t *testing.T) {
+	require := require.New(t)
+
+	r := &Runner{maxRetries: 1, retryBackoff: time.Millisecond, runInterval: time.Hour}
+	r.queue = workqueue.NewRateLimitingQueue(r.syncRateLimiter())
+	defer r.queue.ShutDown()
+
+	// every failed namespace is queued on its own key
+	result := newSyncResult()
+	result.add("default", errors.New("denied by webhook"))
+	result.add("myapp", errors.New("denied by webhook"))
+	r.requeueFailed(syncKey, result, nil)
+	require.Equal(0, r.queue.NumRequeues(syncKey))
+	require.Equal(1, r.queue.NumRequeues(namespaceKey("default")))
+
+	keys := make([]string, 0)
+	for i := 0; i < 2; i++ {
+		key, _ := r.queue.Get()
+		keys = append(keys, key.(string))
+		r.queue.Done(key)
+	}
+	require.ElementsMatch([]string{"namespace/default", "namespace/myapp"}, keys)
+	require.Equal("default", keyNamespace(namespaceKey("default")))
+	require.Equal("", keyNamespace(syncKey))
+
+	// the namespace failing again spends its budget, then waits for the next resync
+	failed := newSyncResult()
+	failed.add("default", errors.New("denied by webhook"))
+	r.requeueFailed(namespaceKey("default"), failed, nil)
+	require.Equal(0, r.queue.NumRequeues(namespaceKey("default")))
+	require.Equal(0, r.queue.Len())
+
+	// a namespace that synced is forgotten
+	r.requeueFailed(namespaceKey("myapp"), newSyncResult(), nil)
+	require.Equal(0, r.queue.NumRequeues(namespaceKey("myapp")))
+
+	// a sync failing as a whole is queued again
+	r.requeueFailed(syncKey, newSyncResult(), errors.New("list namespaces failed"))
+	require.Equal(1, r.queue.NumRequeues(syncKey))
+}
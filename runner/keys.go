@@ -0,0 +1,92 @@
+package runner
+
+import (
+	"path"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Annotations listing the data keys of the global object its copies get, comma separated names or glob patterns
+	includeKeysAnnotationKey = "MakeGlobalKeys"
+	excludeKeysAnnotationKey = "MakeGlobalExcludeKeys"
+)
+
+// keyPatterns returns the key patterns listed in the annotation
+func keyPatterns(object metav1.Object, annotation string) []string {
+	value, ok := object.GetAnnotations()[annotation]
+	if !ok {
+		return nil
+	}
+	patterns := make([]string, 0)
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// checkKeyFilters validates the key patterns, so a bad global object is skipped before reaching any namespace
+func checkKeyFilters(object metav1.Object) error {
+	for _, annotation := range []string{includeKeysAnnotationKey, excludeKeysAnnotationKey} {
+		for _, pattern := range keyPatterns(object, annotation) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func matchesAnyKey(key string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// publishedKey reports if copies get the key, included keys are published unless excluded as well
+func publishedKey(object metav1.Object, key string) bool {
+	if _, ok := object.GetAnnotations()[includeKeysAnnotationKey]; ok && !matchesAnyKey(key, keyPatterns(object, includeKeysAnnotationKey)) {
+		return false
+	}
+	return !matchesAnyKey(key, keyPatterns(object, excludeKeysAnnotationKey))
+}
+
+func filtersKeys(object metav1.Object) bool {
+	_, include := object.GetAnnotations()[includeKeysAnnotationKey]
+	_, exclude := object.GetAnnotations()[excludeKeysAnnotationKey]
+	return include || exclude
+}
+
+// publishedValues returns the values of the published keys
+func publishedValues(object metav1.Object, values map[string]string) map[string]string {
+	if !filtersKeys(object) || values == nil {
+		return values
+	}
+	published := make(map[string]string)
+	for key, value := range values {
+		if publishedKey(object, key) {
+			published[key] = value
+		}
+	}
+	return published
+}
+
+// publishedBytes returns the binary values of the published keys
+func publishedBytes(object metav1.Object, values map[string][]byte) map[string][]byte {
+	if !filtersKeys(object) || values == nil {
+		return values
+	}
+	published := make(map[string][]byte)
+	for key, value := range values {
+		if publishedKey(object, key) {
+			published[key] = value
+		}
+	}
+	return published
+}
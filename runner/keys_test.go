@@ -0,0 +1,102 @@
+package runner_test
+
+import (
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestKeys_Filters(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+
+	global := secret
+	global.ObjectMeta = metav1.ObjectMeta{
+		Name:        "storecreds-global",
+		Namespace:   "myapp",
+		Annotations: map[string]string{"MakeGlobal": "true", "MakeGlobalExcludeKeys": "admin-*"},
+	}
+	global.Data = map[string][]byte{"user": []byte("reader"), "admin-password": []byte("secret")}
+	_, _ = config.Client.Clientset.CoreV1().Secrets("myapp").Create(&global)
+
+	included := configmap
+	included.ObjectMeta = metav1.ObjectMeta{
+		Name:        "storeconfig-global",
+		Namespace:   "myapp",
+		Annotations: map[string]string{"MakeGlobal": "true", "MakeGlobalKeys": "url, timeout"},
+	}
+	included.Data = map[string]string{"url": "https://store.example.com", "timeout": "30s", "debug": "true"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&included)
+
+	broken := configmap
+	broken.ObjectMeta = metav1.ObjectMeta{
+		Name:        "broken-global",
+		Namespace:   "myapp",
+		Annotations: map[string]string{"MakeGlobal": "true", "MakeGlobalKeys": "[url"},
+	}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&broken)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	copied, err := config.Client.Clientset.CoreV1().Secrets("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(map[string][]byte{"user": []byte("reader")}, copied.Data)
+
+	copiedConfig, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(included.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(map[string]string{"url": "https://store.example.com", "timeout": "30s"}, copiedConfig.Data)
+
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get(broken.Name, metav1.GetOptions{})
+	require.Error(err)
+}
+
+func TestKeys_NewlyExcluded(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+
+	global := secret
+	global.ObjectMeta = metav1.ObjectMeta{
+		Name:        "storecreds-global",
+		Namespace:   "myapp",
+		Annotations: map[string]string{"MakeGlobal": "true"},
+	}
+	global.Data = map[string][]byte{"user": []byte("reader"), "admin-password": []byte("secret")}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.CreateSecret("default", global)
+	require.NoError(err)
+	created, err := config.Client.Clientset.CoreV1().Secrets("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Contains(created.Data, "admin-password")
+
+	// the copy only compares and keeps the published keys
+	global.Annotations["MakeGlobalExcludeKeys"] = "admin-password"
+	secretMaps := map[string]*runner.NamepaceSecrets{
+		"default": {Secrets: []v1.Secret{*created}},
+	}
+	err = runr.AddAnnotatedSecret(secretMaps, "default", global)
+	require.NoError(err)
+
+	updated, err := config.Client.Clientset.CoreV1().Secrets("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(map[string][]byte{"user": []byte("reader")}, updated.Data)
+}
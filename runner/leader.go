@@ -0,0 +1,199 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	coordinationv1beta1 "k8s.io/api/coordination/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	coordinationclient "k8s.io/client-go/kubernetes/typed/coordination/v1beta1"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// LeaseLock is a leader election lock stored in a coordination.k8s.io Lease
+type LeaseLock struct {
+	LeaseMeta metav1.ObjectMeta
+	Client    coordinationclient.LeasesGetter
+	identity  string
+	lease     *coordinationv1beta1.Lease
+}
+
+func NewLeaseLock(namespace string, name string, identity string, client coordinationclient.LeasesGetter) *LeaseLock {
+	return &LeaseLock{
+		LeaseMeta: metav1.ObjectMeta{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Client:   client,
+		identity: identity,
+	}
+}
+
+// Get returns the election record from the Lease spec
+func (ll *LeaseLock) Get() (*resourcelock.LeaderElectionRecord, error) {
+	var err error
+	ll.lease, err = ll.Client.Leases(ll.LeaseMeta.Namespace).Get(ll.LeaseMeta.Name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return leaseSpecToRecord(&ll.lease.Spec), nil
+}
+
+// Create attempts to create the Lease holding the election record
+func (ll *LeaseLock) Create(ler resourcelock.LeaderElectionRecord) error {
+	var err error
+	ll.lease, err = ll.Client.Leases(ll.LeaseMeta.Namespace).Create(&coordinationv1beta1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ll.LeaseMeta.Name,
+			Namespace: ll.LeaseMeta.Namespace,
+		},
+		Spec: recordToLeaseSpec(&ler),
+	})
+	return err
+}
+
+// Update writes the election record to the Lease fetched by Get or Create
+func (ll *LeaseLock) Update(ler resourcelock.LeaderElectionRecord) error {
+	if ll.lease == nil {
+		return errors.New("lease not initialized, call get or create first")
+	}
+	ll.lease.Spec = recordToLeaseSpec(&ler)
+
+	var err error
+	ll.lease, err = ll.Client.Leases(ll.LeaseMeta.Namespace).Update(ll.lease)
+	return err
+}
+
+// RecordEvent logs leader election transitions
+func (ll *LeaseLock) RecordEvent(s string) {
+	log.Infof("Leader election: %v %v", ll.identity, s)
+}
+
+// Describe returns namespace/name of the Lease
+func (ll *LeaseLock) Describe() string {
+	return fmt.Sprintf("%v/%v", ll.LeaseMeta.Namespace, ll.LeaseMeta.Name)
+}
+
+// Identity returns the identity of this candidate
+func (ll *LeaseLock) Identity() string {
+	return ll.identity
+}
+
+func leaseSpecToRecord(spec *coordinationv1beta1.LeaseSpec) *resourcelock.LeaderElectionRecord {
+	record := &resourcelock.LeaderElectionRecord{}
+	if spec.HolderIdentity != nil {
+		record.HolderIdentity = *spec.HolderIdentity
+	}
+	if spec.LeaseDurationSeconds != nil {
+		record.LeaseDurationSeconds = int(*spec.LeaseDurationSeconds)
+	}
+	if spec.LeaseTransitions != nil {
+		record.LeaderTransitions = int(*spec.LeaseTransitions)
+	}
+	if spec.AcquireTime != nil {
+		record.AcquireTime = metav1.NewTime(spec.AcquireTime.Time)
+	}
+	if spec.RenewTime != nil {
+		record.RenewTime = metav1.NewTime(spec.RenewTime.Time)
+	}
+	return record
+}
+
+func recordToLeaseSpec(record *resourcelock.LeaderElectionRecord) coordinationv1beta1.LeaseSpec {
+	holder := record.HolderIdentity
+	duration := int32(record.LeaseDurationSeconds)
+	transitions := int32(record.LeaderTransitions)
+	acquired := metav1.NewMicroTime(record.AcquireTime.Time)
+	renewed := metav1.NewMicroTime(record.RenewTime.Time)
+	return coordinationv1beta1.LeaseSpec{
+		HolderIdentity:       &holder,
+		LeaseDurationSeconds: &duration,
+		LeaseTransitions:     &transitions,
+		AcquireTime:          &acquired,
+		RenewTime:            &renewed,
+	}
+}
+
+// runLeaderElection runs the sync loop only while holding the lease,
+// losing the lease stops the loop and returns an error so the process restarts as a standby
+func (r *Runner) runLeaderElection() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-r.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	lock := NewLeaseLock(r.leaseNamespace, r.leaseName, r.identity, r.client.Clientset.CoordinationV1beta1())
+	leading := make(chan struct{})
+	finished := make(chan error, 1)
+
+	elector, err := leaderelection.NewLeaderElector(leaderelection.LeaderElectionConfig{
+		Lock:          lock,
+		LeaseDuration: r.leaseDuration,
+		RenewDeadline: r.renewDeadline,
+		RetryPeriod:   r.retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(context.Context) {
+				log.Infof("Acquired lease %v as %v, starting sync", lock.Describe(), r.identity)
+				close(leading)
+				r.markStandby(false)
+				finished <- r.runLoop()
+				cancel()
+			},
+			OnStoppedLeading: func() {
+				log.Infof("Stopped leading lease %v", lock.Describe())
+			},
+			OnNewLeader: func(identity string) {
+				if identity != r.identity {
+					log.Infof("Standing by, lease %v held by %v", lock.Describe(), identity)
+				}
+			},
+		},
+		Name: r.leaseName,
+	})
+	if err != nil {
+		log.WithError(err).Error("bad leader election configuration")
+		return err
+	}
+
+	log.Infof("Waiting to acquire lease %v as %v", lock.Describe(), r.identity)
+	r.markStandby(true)
+	elector.Run(ctx)
+
+	select {
+	case <-leading:
+	default:
+		// stopped before ever leading
+		return nil
+	}
+
+	stopping := r.isStopped()
+	r.Close()
+	err = <-finished
+	if err != nil || stopping {
+		return err
+	}
+	return errors.New("lost leader election")
+}
+
+// leaderElectionTimings checks the durations the same way the leader elector does
+func leaderElectionTimings(leaseDuration time.Duration, renewDeadline time.Duration, retryPeriod time.Duration) error {
+	if retryPeriod <= 0 {
+		return errors.New("leader election retry period must be greater than zero")
+	}
+	if renewDeadline <= time.Duration(leaderelection.JitterFactor*float64(retryPeriod)) {
+		return errors.New("leader election renew deadline must be greater than retry period * 1.2")
+	}
+	if leaseDuration <= renewDeadline {
+		return errors.New("leader election lease duration must be greater than renew deadline")
+	}
+	return nil
+}
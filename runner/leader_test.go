@@ -0,0 +1,128 @@
+package runner_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	coordinationv1beta1 "k8s.io/api/coordination/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func leaderElectionConfig(client *runner.K8S, identity string) runner.Config {
+	config := *runner.DefaultConfig()
+	config.Client = client
+	config.Debug = true
+	config.RunInterval = 1 * time.Second
+	config.LeaderElect = true
+	config.Identity = identity
+	config.LeaseDuration = 600 * time.Millisecond
+	config.RenewDeadline = 400 * time.Millisecond
+	config.RetryPeriod = 100 * time.Millisecond
+	return config
+}
+
+func TestLeader_Start_Once(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := leaderElectionConfig(fake_simple_client(), "replica-a")
+	config.Once = true
+
+	// create annotated configmap
+	annotatedConfigMap := configmap
+	annotatedConfigMap.ObjectMeta.Name = "storeconfig-global"
+	annotatedConfigMap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&annotatedConfigMap)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	lease, err := config.Client.Clientset.CoordinationV1beta1().Leases(config.LeaseNamespace).Get(config.LeaseName, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal("replica-a", *lease.Spec.HolderIdentity)
+
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get(annotatedConfigMap.Name, metav1.GetOptions{})
+	require.NoError(err)
+}
+
+func TestLeader_Start_Standby(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := leaderElectionConfig(fake_simple_client(), "replica-b")
+
+	// another replica holds the lease
+	holder := "replica-a"
+	duration := int32(60)
+	now := metav1.NewMicroTime(time.Now())
+	_, _ = config.Client.Clientset.CoordinationV1beta1().Leases(config.LeaseNamespace).Create(&coordinationv1beta1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      config.LeaseName,
+			Namespace: config.LeaseNamespace,
+		},
+		Spec: coordinationv1beta1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &duration,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	})
+
+	// create annotated configmap
+	annotatedConfigMap := configmap
+	annotatedConfigMap.ObjectMeta.Name = "storeconfig-global"
+	annotatedConfigMap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&annotatedConfigMap)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+
+	errs := make(chan error)
+	go func() {
+		errs <- runr.Start()
+	}()
+	time.Sleep(500 * time.Millisecond)
+	runr.Close()
+	require.NoError(<-errs)
+
+	// standby never synced
+	_, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(annotatedConfigMap.Name, metav1.GetOptions{})
+	require.Error(err)
+	require.NoError(runr.Healthy())
+}
+
+func TestLeader_Init(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	k8s := fake_clientset()
+	k8s.Clientset.(*fake.Clientset).Fake.AddReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SelfSubjectAccessReview{
+			Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true},
+		}, nil
+	})
+
+	config := leaderElectionConfig(k8s, "replica-a")
+	runr := runner.NewRunner(&config)
+	require.NoError(runr.Init())
+
+	// renew deadline must be shorter than the lease duration
+	config.RenewDeadline = config.LeaseDuration
+	runr = runner.NewRunner(&config)
+	require.Error(runr.Init())
+
+	config = leaderElectionConfig(k8s, "")
+	runr = runner.NewRunner(&config)
+	require.Error(runr.Init())
+}
@@ -0,0 +1,133 @@
+package runner
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	metricsNamespace = "k8s_global_objects"
+)
+
+var (
+	syncDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "sync_duration_seconds",
+		Help:      "Duration of a full global object sync.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	})
+	syncTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "syncs_total",
+		Help:      "Number of global object syncs by result: success, partial or failure.",
+	}, []string{"result"})
+	lastSuccessfulSync = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "last_successful_sync_timestamp_seconds",
+		Help:      "Unix time of the last successful global object sync.",
+	})
+	namespacesScanned = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "namespaces_scanned",
+		Help:      "Number of namespaces scanned by the last global object sync.",
+	})
+	failedNamespaces = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "failed_namespaces",
+		Help:      "Number of namespaces with errors in the last global object sync.",
+	})
+	objectsCreated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "objects_created_total",
+		Help:      "Number of global object copies created by kind.",
+	}, []string{"kind"})
+	objectsUpdated = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "objects_updated_total",
+		Help:      "Number of global object copies updated by kind.",
+	}, []string{"kind"})
+	objectsDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "objects_deleted_total",
+		Help:      "Number of global object copies deleted by kind.",
+	}, []string{"kind"})
+	driftDetected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "drift_detected_total",
+		Help:      "Number of copies found drifted from their global object by kind.",
+	}, []string{"kind"})
+	blockedSources = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "blocked_sources_total",
+		Help:      "Number of global objects ignored because their namespace may not publish them by kind.",
+	}, []string{"kind"})
+	writeRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "write_retries_total",
+		Help:      "Number of retried Kubernetes API writes by verb.",
+	}, []string{"verb"})
+	apiErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "api_errors_total",
+		Help:      "Number of failed Kubernetes API calls by verb.",
+	}, []string{"verb"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		syncDuration,
+		syncTotal,
+		lastSuccessfulSync,
+		namespacesScanned,
+		failedNamespaces,
+		objectsCreated,
+		objectsUpdated,
+		objectsDeleted,
+		driftDetected,
+		blockedSources,
+		writeRetries,
+		apiErrors,
+	)
+}
+
+// MetricsHandler serves the runner metrics in the Prometheus format
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// recordWrite counts the outcome of a create, update or delete call
+func recordWrite(verb string, kind string, err error) {
+	if err != nil {
+		apiErrors.WithLabelValues(verb).Inc()
+		return
+	}
+
+	switch verb {
+	case "create":
+		objectsCreated.WithLabelValues(kind).Inc()
+	case "update":
+		objectsUpdated.WithLabelValues(kind).Inc()
+	case "delete":
+		objectsDeleted.WithLabelValues(kind).Inc()
+	}
+}
+
+// observeSync records the duration and result of a sync that began at started
+func observeSync(started time.Time, result *SyncResult, err error) {
+	syncDuration.Observe(time.Since(started).Seconds())
+	if err != nil {
+		syncTotal.WithLabelValues("failure").Inc()
+		return
+	}
+	namespacesScanned.Set(float64(result.Namespaces))
+	failedNamespaces.Set(float64(len(result.Errors)))
+	if len(result.Errors) > 0 {
+		syncTotal.WithLabelValues("partial").Inc()
+		return
+	}
+	syncTotal.WithLabelValues("success").Inc()
+	lastSuccessfulSync.SetToCurrentTime()
+}
@@ -0,0 +1,87 @@
+package runner
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestMetrics_Writes(t *testing.T) {
+	require := require.New(t)
+
+	config := *DefaultConfig()
+	config.Client = &K8S{
+		Clientset: fake.NewSimpleClientset(),
+	}
+	r := NewRunner(&config)
+
+	global := v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "storeconfig-global",
+			Namespace: "myapp",
+		},
+		Data: map[string]string{"key": "value"},
+	}
+
+	created := testutil.ToFloat64(objectsCreated.WithLabelValues("ConfigMap"))
+	updated := testutil.ToFloat64(objectsUpdated.WithLabelValues("ConfigMap"))
+	deleted := testutil.ToFloat64(objectsDeleted.WithLabelValues("ConfigMap"))
+	createErrors := testutil.ToFloat64(apiErrors.WithLabelValues("create"))
+
+	require.NoError(r.CreateConfigMap("default", global))
+	require.NoError(r.UpdateConfigMap("default", global))
+	require.NoError(r.DeleteConfigMap("default", global))
+	require.NoError(r.CreateConfigMap("default", global))
+	require.Error(r.CreateConfigMap("default", global))
+
+	require.Equal(created+2, testutil.ToFloat64(objectsCreated.WithLabelValues("ConfigMap")))
+	require.Equal(updated+1, testutil.ToFloat64(objectsUpdated.WithLabelValues("ConfigMap")))
+	require.Equal(deleted+1, testutil.ToFloat64(objectsDeleted.WithLabelValues("ConfigMap")))
+	require.Equal(createErrors+1, testutil.ToFloat64(apiErrors.WithLabelValues("create")))
+}
+
+func TestMetrics_observeSync(t *testing.T) {
+	require := require.New(t)
+
+	successes := testutil.ToFloat64(syncTotal.WithLabelValues("success"))
+	failures := testutil.ToFloat64(syncTotal.WithLabelValues("failure"))
+	partials := testutil.ToFloat64(syncTotal.WithLabelValues("partial"))
+
+	observeSync(time.Now(), newSyncResult(), errors.New("sync failed"))
+	require.Equal(failures+1, testutil.ToFloat64(syncTotal.WithLabelValues("failure")))
+	require.Equal(successes, testutil.ToFloat64(syncTotal.WithLabelValues("success")))
+
+	result := newSyncResult()
+	result.Namespaces = 3
+	observeSync(time.Now(), result, nil)
+	require.Equal(successes+1, testutil.ToFloat64(syncTotal.WithLabelValues("success")))
+	require.Equal(float64(3), testutil.ToFloat64(namespacesScanned))
+	require.InDelta(float64(time.Now().Unix()), testutil.ToFloat64(lastSuccessfulSync), 5)
+
+	// a partial sync is not successful
+	lastSuccess := testutil.ToFloat64(lastSuccessfulSync)
+	result.add("default", errors.New("denied by webhook"))
+	observeSync(time.Now(), result, nil)
+	require.Equal(lastSuccess, testutil.ToFloat64(lastSuccessfulSync))
+	require.Equal(partials+1, testutil.ToFloat64(syncTotal.WithLabelValues("partial")))
+	require.Equal(float64(1), testutil.ToFloat64(failedNamespaces))
+}
+
+func TestMetrics_Handler(t *testing.T) {
+	require := require.New(t)
+
+	observeSync(time.Now(), newSyncResult(), nil)
+
+	recorder := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	require.Equal(200, recorder.Code)
+	require.Contains(recorder.Body.String(), "k8s_global_objects_sync_duration_seconds")
+	require.Contains(recorder.Body.String(), "k8s_global_objects_last_successful_sync_timestamp_seconds")
+}
@@ -0,0 +1,58 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// Annotation holding a Go template for the name of the copies, the copies are named like the global object without it
+	nameTemplateAnnotationKey = "MakeGlobalName"
+)
+
+// NameTemplateData is what a MakeGlobalName template can use
+type NameTemplateData struct {
+	// Name of the global object
+	Name string
+	// Namespace of the global object
+	SourceNamespace string
+}
+
+// targetName renders the name copies of the global object get
+func targetName(object metav1.Object) (string, error) {
+	text, ok := object.GetAnnotations()[nameTemplateAnnotationKey]
+	if !ok {
+		return object.GetName(), nil
+	}
+
+	tmpl, err := template.New(nameTemplateAnnotationKey).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var name bytes.Buffer
+	err = tmpl.Execute(&name, NameTemplateData{
+		Name:            object.GetName(),
+		SourceNamespace: object.GetNamespace(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if errs := validation.IsDNS1123Subdomain(name.String()); len(errs) > 0 {
+		return "", fmt.Errorf("bad name %q: %v", name.String(), strings.Join(errs, ", "))
+	}
+	return name.String(), nil
+}
+
+// copyName returns the name of the copies of the object, bad templates are reported when the global object is found
+func copyName(object metav1.Object) string {
+	name, err := targetName(object)
+	if err != nil {
+		return object.GetName()
+	}
+	return name
+}
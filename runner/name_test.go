@@ -0,0 +1,120 @@
+package runner_test
+
+import (
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestName_Template(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{
+		Name:      "storeconfig-global",
+		Namespace: "myapp",
+		Annotations: map[string]string{
+			"MakeGlobal":     "true",
+			"MakeGlobalName": "{{ .SourceNamespace }}-{{ .Name }}",
+		},
+	}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&global)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	res, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get("myapp-storeconfig-global", metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(global.Data, res.Data)
+	require.Equal("storeconfig-global", res.Annotations["GlobalSourceName"])
+	require.NotContains(res.Annotations, "MakeGlobalName")
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get(global.Name, metav1.GetOptions{})
+	require.Error(err)
+}
+
+func TestName_BadTemplate(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+
+	for name, template := range map[string]string{
+		"unknown-field": "{{ .Namespace }}-{{ .Name }}",
+		"bad-name":      "{{ .Name }}_copy",
+		"bad-syntax":    "{{ .Name",
+	} {
+		global := configmap
+		global.ObjectMeta = metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   "myapp",
+			Annotations: map[string]string{"MakeGlobal": "true", "MakeGlobalName": template},
+		}
+		_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&global)
+	}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	list, err := config.Client.Clientset.CoreV1().ConfigMaps("default").List(metav1.ListOptions{LabelSelector: "CreatedBy=k8s-global-objects"})
+	require.NoError(err)
+	require.Empty(list.Items)
+}
+
+func TestName_Renamed(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{
+		Name:        "storeconfig-global",
+		Namespace:   "myapp",
+		Annotations: map[string]string{"MakeGlobal": "true", "MakeGlobalName": "storeconfig"},
+	}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.CreateConfigMap("default", global)
+	require.NoError(err)
+	old, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get("storeconfig", metav1.GetOptions{})
+	require.NoError(err)
+
+	// the copy under the old name is orphaned once the template changes
+	global.Annotations["MakeGlobalName"] = "store-{{ .Name }}"
+	configMapMaps := map[string]*runner.NamespaceConfigMaps{
+		"default": {Configmaps: []v1.ConfigMap{*old}},
+		"myapp":   {Configmaps: []v1.ConfigMap{global}},
+	}
+	err = runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	err = runr.RemoveOrphanedConfigMaps(configMapMaps, "default")
+	require.NoError(err)
+
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get("store-storeconfig-global", metav1.GetOptions{})
+	require.NoError(err)
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get("storeconfig", metav1.GetOptions{})
+	require.Error(err)
+}
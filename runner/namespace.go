@@ -1,6 +1,9 @@
 package runner
 
 import (
+	"path"
+	"strconv"
+
 	log "github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -15,3 +18,26 @@ func (r *Runner) NamespacesList() (namespaces *v1.NamespaceList, err error) {
 
 	return namespaces, nil
 }
+
+func (r *Runner) namespaceExcluded(namespace v1.Namespace) bool {
+	for _, pattern := range r.excludeNamespaces {
+		// patterns are validated in Init
+		if matched, _ := path.Match(pattern, namespace.Name); matched {
+			log.Debugf("Namespace %v excluded by pattern %v", namespace.Name, pattern)
+			return true
+		}
+	}
+
+	if chkAnnotation, ok := namespace.Annotations[excludeNamespaceAnnotationKey]; ok {
+		excluded, err := strconv.ParseBool(chkAnnotation)
+		if err != nil {
+			log.WithError(err).Errorf("bad %v annotation in namespace %v", excludeNamespaceAnnotationKey, namespace.Name)
+			return false
+		}
+		if excluded {
+			log.Debugf("Namespace %v excluded by %v annotation", namespace.Name, excludeNamespaceAnnotationKey)
+		}
+		return excluded
+	}
+	return false
+}
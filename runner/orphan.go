@@ -0,0 +1,100 @@
+package runner
+
+import (
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// orphanedCopy reports if object is a runner copy whose global object is gone, no longer annotated
+// or now names its copies differently, source is nil when the global object was not found
+func orphanedCopy(object metav1.Object, source metav1.Object) bool {
+	if !isCreatedByRunner(object) {
+		return false
+	}
+	// copies made before source tracking can not be traced back
+	if _, ok := object.GetAnnotations()[sourceNameAnnotationKey]; !ok {
+		return false
+	}
+	if source == nil {
+		return true
+	}
+	if _, annotated := source.GetAnnotations()[annotationKey]; !annotated {
+		return true
+	}
+	// a bad name template keeps the copies until it is fixed
+	name, err := targetName(source)
+	return err == nil && name != object.GetName()
+}
+
+// orphanReady reports if the orphan was seen for longer than the grace period
+func (r *Runner) orphanReady(key string) bool {
+	r.orphansSeen[key] = true
+
+	firstSeen, ok := r.orphans[key]
+	if !ok {
+		firstSeen = time.Now()
+		r.orphans[key] = firstSeen
+	}
+	if time.Since(firstSeen) < r.orphanGracePeriod {
+		log.Debugf("Orphaned copy %v within grace period, first seen %v", key, firstSeen.Format(time.RFC3339))
+		return false
+	}
+	return true
+}
+
+// forgetOrphans drops orphans that were not seen in the last sync
+func (r *Runner) forgetOrphans() {
+	for key := range r.orphans {
+		if !r.orphansSeen[key] {
+			delete(r.orphans, key)
+		}
+	}
+	r.orphansSeen = make(map[string]bool)
+}
+
+// removeOrphaned deletes the copies in the namespace whose global object is gone
+func (r *Runner) removeOrphaned(k *syncKind, namespace string) error {
+	errs := make([]error, 0)
+	for _, object := range k.objects[namespace] {
+		annotations := object.GetAnnotations()
+		var source metav1.Object
+		// global objects of namespaces not allowed to publish them count as gone
+		if !r.blockedSources[annotations[sourceNamespaceAnnotationKey]] {
+			source = k.object(annotations[sourceNamespaceAnnotationKey], annotations[sourceNameAnnotationKey])
+		}
+
+		if !orphanedCopy(object, source) || !r.orphanReady(k.gvr.Resource+"/"+namespace+"/"+object.GetName()) {
+			continue
+		}
+
+		kind := k.kindOf(object)
+		log.Infof("Removing orphaned Global Object copy %v from namespace %v", object.GetSelfLink(), namespace)
+		err := k.delete(namespace, object)
+		// events go to the global object, a deleted one can not get any
+		if source != nil && !apierrors.IsNotFound(err) {
+			r.reportWrite(source, "delete", kind, namespace, err)
+		}
+		if err != nil && !apierrors.IsNotFound(err) {
+			log.WithError(err).Errorf("Failed removing orphaned %v %v from namespace %v", kind, object.GetName(), namespace)
+			errs = append(errs, err)
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+func (r *Runner) RemoveOrphanedConfigMaps(configMapMaps map[string]*NamespaceConfigMaps, namespace string) error {
+	return r.removeOrphaned(r.configMapsOf(configMapMaps), namespace)
+}
+
+func (r *Runner) RemoveOrphanedSecrets(secretMaps map[string]*NamepaceSecrets, namespace string) error {
+	return r.removeOrphaned(r.secretsOf(secretMaps), namespace)
+}
+
+func (r *Runner) RemoveOrphanedResources(gvr schema.GroupVersionResource, resourceMaps map[string]*NamespaceResources, namespace string) error {
+	return r.removeOrphaned(r.resourcesOf(gvr, resourceMaps), namespace)
+}
@@ -0,0 +1,150 @@
+package runner_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func orphanedConfigMap(name string, sourceNamespace string, sourceName string) *v1.ConfigMap {
+	orphan := configmap
+	orphan.ObjectMeta.Name = name
+	orphan.ObjectMeta.Labels = map[string]string{"CreatedBy": "k8s-global-objects"}
+	orphan.ObjectMeta.Annotations = map[string]string{
+		"GlobalSourceNamespace": sourceNamespace,
+		"GlobalSourceName":      sourceName,
+	}
+	return &orphan
+}
+
+func TestOrphan_RemoveOrphanedConfigMaps(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Debug = true
+
+	// global object that is no longer annotated
+	unannotated := configmap
+	unannotated.ObjectMeta.Name = "was-global"
+	unannotated.ObjectMeta.Namespace = "myapp"
+	// global object that is still annotated
+	annotated := configmap
+	annotated.ObjectMeta.Name = "still-global"
+	annotated.ObjectMeta.Namespace = "myapp"
+	annotated.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+
+	copies := []*v1.ConfigMap{
+		orphanedConfigMap("deleted-global", "myapp", "deleted-global"),
+		orphanedConfigMap("was-global", "myapp", "was-global"),
+		orphanedConfigMap("still-global", "myapp", "still-global"),
+	}
+	// user object pointing to a missing global object is left alone
+	userObject := orphanedConfigMap("user-object", "myapp", "deleted-global")
+	userObject.ObjectMeta.Labels = nil
+	copies = append(copies, userObject)
+
+	namespaceConfigMaps := make([]v1.ConfigMap, 0)
+	for _, copied := range copies {
+		_, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Create(copied)
+		require.NoError(err)
+		namespaceConfigMaps = append(namespaceConfigMaps, *copied)
+	}
+
+	configMapMaps := map[string]*runner.NamespaceConfigMaps{
+		"default": {Configmaps: namespaceConfigMaps},
+		"myapp":   {Configmaps: []v1.ConfigMap{unannotated, annotated}},
+	}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.RemoveOrphanedConfigMaps(configMapMaps, "default")
+	require.NoError(err)
+
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get("deleted-global", metav1.GetOptions{})
+	require.Error(err)
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get("was-global", metav1.GetOptions{})
+	require.Error(err)
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get("still-global", metav1.GetOptions{})
+	require.NoError(err)
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get("user-object", metav1.GetOptions{})
+	require.NoError(err)
+}
+
+func TestOrphan_GracePeriod(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Debug = true
+	config.OrphanGracePeriod = 100 * time.Millisecond
+
+	orphan := orphanedConfigMap("deleted-global", "myapp", "deleted-global")
+	_, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Create(orphan)
+	require.NoError(err)
+
+	configMapMaps := map[string]*runner.NamespaceConfigMaps{
+		"default": {Configmaps: []v1.ConfigMap{*orphan}},
+		"myapp":   {Configmaps: []v1.ConfigMap{}},
+	}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	// first seen - kept
+	err = runr.RemoveOrphanedConfigMaps(configMapMaps, "default")
+	require.NoError(err)
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get(orphan.Name, metav1.GetOptions{})
+	require.NoError(err)
+
+	// grace period over - removed
+	time.Sleep(150 * time.Millisecond)
+	err = runr.RemoveOrphanedConfigMaps(configMapMaps, "default")
+	require.NoError(err)
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get(orphan.Name, metav1.GetOptions{})
+	require.Error(err)
+}
+
+func TestOrphan_Start(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Debug = true
+	config.Once = true
+	config.RunInterval = 1 * time.Millisecond
+
+	orphan := orphanedConfigMap("deleted-global", "myapp", "deleted-global")
+	_, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Create(orphan)
+	require.NoError(err)
+
+	orphanSecret := secret
+	orphanSecret.ObjectMeta.Name = "deleted-global"
+	orphanSecret.ObjectMeta.Labels = map[string]string{"CreatedBy": "k8s-global-objects"}
+	orphanSecret.ObjectMeta.Annotations = map[string]string{"GlobalSourceNamespace": "gone", "GlobalSourceName": "deleted-global"}
+	_, err = config.Client.Clientset.CoreV1().Secrets("default").Create(&orphanSecret)
+	require.NoError(err)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err = runr.Start()
+	require.NoError(err)
+
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get(orphan.Name, metav1.GetOptions{})
+	require.Error(err)
+	_, err = config.Client.Clientset.CoreV1().Secrets("default").Get(orphanSecret.Name, metav1.GetOptions{})
+	require.Error(err)
+}
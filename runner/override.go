@@ -0,0 +1,68 @@
+package runner
+
+import (
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Annotation marking a ConfigMap as the override of a global ConfigMap copy in its namespace
+	overrideAnnotationKey = "MakeGlobalOverride"
+	// Suffix of the override name, added to the name of the copy
+	overrideSuffix = "-override"
+)
+
+// overrideName returns the name of the override for a copy
+func overrideName(copyName string) string {
+	return copyName + overrideSuffix
+}
+
+// isOverride reports if the ConfigMap is marked as an override
+func isOverride(object metav1.Object) bool {
+	enabled, err := strconv.ParseBool(object.GetAnnotations()[overrideAnnotationKey])
+	return err == nil && enabled
+}
+
+// configMapOverride returns the override of the copy in the namespace, nil when there is none
+func (r *Runner) configMapOverride(namespace string, copyName string) (*v1.ConfigMap, error) {
+	var override *v1.ConfigMap
+	var err error
+	if r.configMapLister != nil {
+		override, err = r.configMapLister.ConfigMaps(namespace).Get(overrideName(copyName))
+	} else {
+		override, err = r.client.Clientset.CoreV1().ConfigMaps(namespace).Get(overrideName(copyName), metav1.GetOptions{})
+	}
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	// a same named ConfigMap without the marker is left alone
+	if !isOverride(override) {
+		return nil, nil
+	}
+	return override, nil
+}
+
+// overrideData merges the override of the copy in the namespace on top of the global data
+func (r *Runner) overrideData(namespace string, copyName string, data map[string]string) (map[string]string, error) {
+	override, err := r.configMapOverride(namespace, copyName)
+	if err != nil || override == nil || len(override.Data) == 0 {
+		return data, err
+	}
+
+	log.Debugf("Merging override %v into ConfigMap %v in namespace %v", override.Name, copyName, namespace)
+	merged := make(map[string]string, len(data)+len(override.Data))
+	for key, value := range data {
+		merged[key] = value
+	}
+	for key, value := range override.Data {
+		merged[key] = value
+	}
+	return merged, nil
+}
@@ -0,0 +1,100 @@
+package runner_test
+
+import (
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestOverride_Merge(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{Name: "storeconfig-global", Namespace: "myapp", Annotations: map[string]string{"MakeGlobal": "true"}}
+	global.Data = map[string]string{"url": "https://store.example.com", "timeout": "30s"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&global)
+
+	override := v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "storeconfig-global-override", Namespace: "default", Annotations: map[string]string{"MakeGlobalOverride": "true"}},
+		Data:       map[string]string{"timeout": "5m"},
+	}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("default").Create(&override)
+
+	// without the marker the ConfigMap is not an override
+	unmarked := override
+	unmarked.ObjectMeta = metav1.ObjectMeta{Name: "storeconfig-global-override", Namespace: appNamespace}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Create(&unmarked)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	res, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(map[string]string{"url": "https://store.example.com", "timeout": "5m"}, res.Data)
+
+	res, err = config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(global.Data, res.Data)
+}
+
+func TestOverride_NotDrift(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{Name: "storeconfig-global", Namespace: "myapp", Annotations: map[string]string{"MakeGlobal": "true"}}
+	global.Data = map[string]string{"url": "https://store.example.com", "timeout": "30s"}
+
+	override := v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "storeconfig-global-override", Namespace: "default", Annotations: map[string]string{"MakeGlobalOverride": "true"}},
+		Data:       map[string]string{"timeout": "5m"},
+	}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("default").Create(&override)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.CreateConfigMap("default", global)
+	require.NoError(err)
+	created, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+
+	// the overridden key is kept
+	fakeClient := config.Client.Clientset.(*fake.Clientset)
+	fakeClient.ClearActions()
+	configMapMaps := map[string]*runner.NamespaceConfigMaps{
+		"default": {Configmaps: []v1.ConfigMap{*created, override}},
+	}
+	err = runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	for _, action := range fakeClient.Actions() {
+		require.NotEqual("update", action.GetVerb())
+	}
+
+	// removing the override restores the global value
+	err = config.Client.Clientset.CoreV1().ConfigMaps("default").Delete(override.Name, &metav1.DeleteOptions{})
+	require.NoError(err)
+	err = runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	updated, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(global.Data, updated.Data)
+}
@@ -0,0 +1,95 @@
+package runner
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// ConflictPolicy decides what happens to same named objects the runner did not create
+type ConflictPolicy string
+
+const (
+	// ConflictSkip leaves objects the runner did not create alone
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictAdopt takes over objects the runner did not create when their content already matches
+	ConflictAdopt ConflictPolicy = "adopt"
+	// ConflictOverwrite takes over, updates and removes any same named object
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+func ParseConflictPolicy(value string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(value); policy {
+	case ConflictSkip, ConflictAdopt, ConflictOverwrite:
+		return policy, nil
+	}
+	return "", fmt.Errorf("bad conflict policy %q, expecting %v, %v or %v", value, ConflictSkip, ConflictAdopt, ConflictOverwrite)
+}
+
+const (
+	// Label stamped on every copy made by the runner
+	createdByLabelKey   = "CreatedBy"
+	createdByLabelValue = "k8s-global-objects"
+	// Annotations tracing a copy back to its global object
+	sourceNamespaceAnnotationKey       = "GlobalSourceNamespace"
+	sourceNameAnnotationKey            = "GlobalSourceName"
+	sourceUIDAnnotationKey             = "GlobalSourceUID"
+	sourceResourceVersionAnnotationKey = "GlobalSourceResourceVersion"
+)
+
+func ownershipLabels() map[string]string {
+	return map[string]string{
+		createdByLabelKey: createdByLabelValue,
+	}
+}
+
+func sourceAnnotations(from metav1.Object) map[string]string {
+	return map[string]string{
+		sourceNamespaceAnnotationKey:       from.GetNamespace(),
+		sourceNameAnnotationKey:            from.GetName(),
+		sourceUIDAnnotationKey:             string(from.GetUID()),
+		sourceResourceVersionAnnotationKey: from.GetResourceVersion(),
+	}
+}
+
+// isCreatedByRunner reports if the object carries the runner label
+func isCreatedByRunner(object metav1.Object) bool {
+	return object.GetLabels()[createdByLabelKey] == createdByLabelValue
+}
+
+// isCopyOf reports if the object is a copy the runner made from source
+func isCopyOf(object metav1.Object, source metav1.Object) bool {
+	annotations := object.GetAnnotations()
+	return isCreatedByRunner(object) &&
+		annotations[sourceNamespaceAnnotationKey] == source.GetNamespace() &&
+		annotations[sourceNameAnnotationKey] == source.GetName() &&
+		annotations[sourceUIDAnnotationKey] == string(source.GetUID())
+}
+
+// canWrite reports if the runner may update or delete the existing object in a target namespace
+func (r *Runner) canWrite(existing metav1.Object, sameContent bool) bool {
+	if isCreatedByRunner(existing) {
+		return true
+	}
+
+	switch r.conflictPolicy {
+	case ConflictOverwrite:
+		return true
+	case ConflictAdopt:
+		return sameContent
+	}
+	return false
+}
+
+func (r *Runner) reportConflict(existing metav1.Object, globalObject metav1.Object) {
+	r.conflicts++
+	log.Warnf("Conflict: %v/%v was not created by k8s-global-objects - leaving it alone instead of syncing %v (conflict policy %v)",
+		existing.GetNamespace(), existing.GetName(), globalObject.GetSelfLink(), r.conflictPolicy)
+	if object, ok := globalObject.(runtime.Object); ok {
+		r.event(object, v1.EventTypeWarning, reasonConflict, "%v/%v was not created by k8s-global-objects, left alone (conflict policy %v)",
+			existing.GetNamespace(), existing.GetName(), r.conflictPolicy)
+	}
+}
@@ -0,0 +1,47 @@
+package runner
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestOwnership_isCopyOf(t *testing.T) {
+	require := require.New(t)
+
+	source := v1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "storeconfig-global",
+			Namespace:       "myapp",
+			UID:             "1234",
+			ResourceVersion: "42",
+		},
+	}
+
+	config := DefaultConfig()
+	config.Client = &K8S{Clientset: fake.NewSimpleClientset()}
+	copied, err := NewRunner(config).createConfigMapObject("default", source)
+	require.NoError(err)
+	require.True(isCreatedByRunner(copied))
+	require.True(isCopyOf(copied, &source))
+	require.Equal("myapp", copied.Annotations[sourceNamespaceAnnotationKey])
+	require.Equal("storeconfig-global", copied.Annotations[sourceNameAnnotationKey])
+	require.Equal("1234", copied.Annotations[sourceUIDAnnotationKey])
+	require.Equal("42", copied.Annotations[sourceResourceVersionAnnotationKey])
+
+	// source changes do not make it someone elses copy
+	source.ResourceVersion = "43"
+	require.True(isCopyOf(copied, &source))
+
+	// recreated source
+	source.UID = "5678"
+	require.False(isCopyOf(copied, &source))
+
+	// user created object with the same name
+	userObject := v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "storeconfig-global"}}
+	require.False(isCreatedByRunner(&userObject))
+	require.False(isCopyOf(&userObject, &source))
+}
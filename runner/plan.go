@@ -0,0 +1,114 @@
+package runner
+
+import (
+	"reflect"
+	"sort"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PlannedChange is a write a dry run skipped
+type PlannedChange struct {
+	Action    string `json:"action"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Global object as namespace/name
+	Source string `json:"source"`
+	// Data keys only, values are never part of the plan
+	Added   []string `json:"added,omitempty"`
+	Changed []string `json:"changed,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// Plan lists the writes of one dry run sync
+type Plan struct {
+	Changes []PlannedChange `json:"changes"`
+}
+
+func newPlan() *Plan {
+	return &Plan{
+		Changes: make([]PlannedChange, 0),
+	}
+}
+
+// LastPlan returns the plan of the last dry run sync
+func (r *Runner) LastPlan() *Plan {
+	r.planLock.Lock()
+	defer r.planLock.Unlock()
+	return r.lastPlan
+}
+
+// planChange records a write instead of making it, before is nil for creates and after is nil for deletes
+func (r *Runner) planChange(action string, kind string, namespace string, from metav1.Object, before map[string]interface{}, after map[string]interface{}) {
+	source := from.GetNamespace() + "/" + from.GetName()
+	// orphaned copies point to their global object
+	if name, ok := from.GetAnnotations()[sourceNameAnnotationKey]; ok {
+		source = from.GetAnnotations()[sourceNamespaceAnnotationKey] + "/" + name
+	}
+
+	change := PlannedChange{
+		Action:    action,
+		Kind:      kind,
+		Namespace: namespace,
+		Name:      copyName(from),
+		Source:    source,
+	}
+	change.Added, change.Changed, change.Removed = diffKeys(before, after)
+
+	log.WithFields(log.Fields{
+		"action":    change.Action,
+		"kind":      change.Kind,
+		"namespace": change.Namespace,
+		"name":      change.Name,
+		"source":    change.Source,
+		"added":     change.Added,
+		"changed":   change.Changed,
+		"removed":   change.Removed,
+	}).Info("Dry run - planned change")
+
+	r.plan.Changes = append(r.plan.Changes, change)
+}
+
+// diffKeys returns the sorted keys added, changed and removed going from before to after
+func diffKeys(before map[string]interface{}, after map[string]interface{}) (added []string, changed []string, removed []string) {
+	for key, value := range after {
+		old, ok := before[key]
+		if !ok {
+			added = append(added, key)
+		} else if !reflect.DeepEqual(old, value) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			removed = append(removed, key)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(changed)
+	sort.Strings(removed)
+	return added, changed, removed
+}
+
+// configMapContent merges data and binaryData, keys are unique across both
+func configMapContent(configMap *v1.ConfigMap) map[string]interface{} {
+	content := make(map[string]interface{}, len(configMap.Data)+len(configMap.BinaryData))
+	for key, value := range configMap.Data {
+		content[key] = value
+	}
+	for key, value := range configMap.BinaryData {
+		content[key] = value
+	}
+	return content
+}
+
+func byteData(data map[string][]byte) map[string]interface{} {
+	content := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		content[key] = value
+	}
+	return content
+}
@@ -0,0 +1,111 @@
+package runner_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPlan_Start_DryRun(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Debug = true
+	config.Once = true
+	config.DryRun = true
+	config.RunInterval = 1 * time.Millisecond
+
+	// create annotated configmap
+	annotatedConfigMap := configmap
+	annotatedConfigMap.ObjectMeta.Name = "storeconfig-global"
+	annotatedConfigMap.ObjectMeta.Namespace = "myapp"
+	annotatedConfigMap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	annotatedConfigMap.Data = map[string]string{"some": "data", "new": "key"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&annotatedConfigMap)
+
+	// drifted copy
+	drifted := configmap
+	drifted.ObjectMeta.Name = annotatedConfigMap.Name
+	drifted.ObjectMeta.Labels = map[string]string{"CreatedBy": "k8s-global-objects"}
+	drifted.Data = map[string]string{"some": "old data", "stale": "key"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("default").Create(&drifted)
+
+	// orphaned copy
+	orphan := orphanedConfigMap("was-global", "myapp", "was-global")
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Create(orphan)
+
+	// copy of a global object no longer global, holding only its published key
+	retired := configmap
+	retired.ObjectMeta.Name = "retired-global"
+	retired.ObjectMeta.Namespace = "myapp"
+	retired.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "false", "MakeGlobalKeys": "some"}
+	retired.Data = map[string]string{"some": "data", "internal": "data"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&retired)
+	retiredCopy := configmap
+	retiredCopy.ObjectMeta.Name = retired.Name
+	retiredCopy.ObjectMeta.Labels = map[string]string{"CreatedBy": "k8s-global-objects"}
+	retiredCopy.ObjectMeta.Annotations = map[string]string{"GlobalSourceNamespace": "myapp", "GlobalSourceName": retired.Name, "GlobalSourceUID": ""}
+	retiredCopy.Data = map[string]string{"some": "data"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("default").Create(&retiredCopy)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	// nothing was written
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Get(annotatedConfigMap.Name, metav1.GetOptions{})
+	require.Error(err)
+	unchanged, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(drifted.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(drifted.Data, unchanged.Data)
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Get(orphan.Name, metav1.GetOptions{})
+	require.NoError(err)
+
+	plan := runr.LastPlan()
+	require.NotNil(plan)
+	require.Len(plan.Changes, 4)
+	require.Contains(plan.Changes, runner.PlannedChange{
+		Action:    "update",
+		Kind:      "ConfigMap",
+		Namespace: "default",
+		Name:      annotatedConfigMap.Name,
+		Source:    "myapp/storeconfig-global",
+		Added:     []string{"new"},
+		Changed:   []string{"some"},
+		Removed:   []string{"stale"},
+	})
+	require.Contains(plan.Changes, runner.PlannedChange{
+		Action:    "create",
+		Kind:      "ConfigMap",
+		Namespace: appNamespace,
+		Name:      annotatedConfigMap.Name,
+		Source:    "myapp/storeconfig-global",
+		Added:     []string{"new", "some"},
+	})
+	require.Contains(plan.Changes, runner.PlannedChange{
+		Action:    "delete",
+		Kind:      "ConfigMap",
+		Namespace: appNamespace,
+		Name:      orphan.Name,
+		Source:    "myapp/was-global",
+		Removed:   []string{"some"},
+	})
+	// the keys of the copy, not of the global object
+	require.Contains(plan.Changes, runner.PlannedChange{
+		Action:    "delete",
+		Kind:      "ConfigMap",
+		Namespace: "default",
+		Name:      retired.Name,
+		Source:    "myapp/retired-global",
+		Removed:   []string{"some"},
+	})
+}
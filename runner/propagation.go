@@ -0,0 +1,142 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PropagationPolicy decides which labels and annotations of a global object its copies get
+type PropagationPolicy string
+
+const (
+	// PropagateNever only puts the runner labels and annotations on copies
+	PropagateNever PropagationPolicy = "never"
+	// PropagatePrefix copies the labels and annotations starting with one of the configured prefixes
+	PropagatePrefix PropagationPolicy = "prefix"
+	// PropagateAll copies every label and annotation
+	PropagateAll PropagationPolicy = "all"
+)
+
+func ParsePropagationPolicy(value string) (PropagationPolicy, error) {
+	switch policy := PropagationPolicy(value); policy {
+	case PropagateNever, PropagatePrefix, PropagateAll:
+		return policy, nil
+	}
+	return "", fmt.Errorf("bad propagation policy %q, expecting %v, %v or %v", value, PropagateNever, PropagatePrefix, PropagateAll)
+}
+
+const (
+	// Annotations listing the propagated keys, so keys dropped from the global object are removed from its copies
+	propagatedLabelsAnnotationKey      = "GlobalPropagatedLabels"
+	propagatedAnnotationsAnnotationKey = "GlobalPropagatedAnnotations"
+	// kubectl apply state of the global object, meaningless on a copy
+	lastAppliedAnnotationKey = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// runnerAnnotation reports if the annotation configures the runner or is set by it, these are never propagated
+func runnerAnnotation(key string) bool {
+	// MakeGlobal and every MakeGlobal* option, a copy must not become a global object
+	if strings.HasPrefix(key, annotationKey) {
+		return true
+	}
+	switch key {
+	case sourceNamespaceAnnotationKey, sourceNameAnnotationKey, sourceUIDAnnotationKey, sourceResourceVersionAnnotationKey,
+		propagatedLabelsAnnotationKey, propagatedAnnotationsAnnotationKey, lastAppliedAnnotationKey, syncStatusAnnotationKey:
+		return true
+	}
+	return false
+}
+
+// propagate returns the values the propagation policy copies, skipping the runner keys
+func (r *Runner) propagate(values map[string]string, skip func(key string) bool) map[string]string {
+	propagated := make(map[string]string)
+	if r.propagationPolicy != PropagateAll && r.propagationPolicy != PropagatePrefix {
+		return propagated
+	}
+	for key, value := range values {
+		if skip(key) || (r.propagationPolicy == PropagatePrefix && !hasAnyPrefix(key, r.propagatePrefixes)) {
+			continue
+		}
+		propagated[key] = value
+	}
+	return propagated
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyLabels returns the labels of a copy of from
+func (r *Runner) copyLabels(from metav1.Object) map[string]string {
+	labels := r.propagate(from.GetLabels(), func(key string) bool { return key == createdByLabelKey })
+	for key, value := range ownershipLabels() {
+		labels[key] = value
+	}
+	return labels
+}
+
+// copyAnnotations returns the annotations of a copy of from, recording which keys were propagated
+func (r *Runner) copyAnnotations(from metav1.Object) map[string]string {
+	labels := r.propagate(from.GetLabels(), func(key string) bool { return key == createdByLabelKey })
+	annotations := r.propagate(from.GetAnnotations(), runnerAnnotation)
+	propagatedAnnotations := keyList(annotations)
+
+	if len(labels) > 0 {
+		annotations[propagatedLabelsAnnotationKey] = keyList(labels)
+	}
+	if len(propagatedAnnotations) > 0 {
+		annotations[propagatedAnnotationsAnnotationKey] = propagatedAnnotations
+	}
+	for key, value := range sourceAnnotations(from) {
+		annotations[key] = value
+	}
+	return annotations
+}
+
+// keyList returns the sorted comma separated keys
+func keyList(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// stalePropagated returns the keys existing got from an earlier propagation that desired no longer propagates
+func stalePropagated(existing metav1.Object, desired metav1.Object) (labels []string, annotations []string) {
+	for _, key := range splitKeys(existing.GetAnnotations()[propagatedLabelsAnnotationKey]) {
+		if _, ok := desired.GetLabels()[key]; !ok {
+			labels = append(labels, key)
+		}
+	}
+	for _, key := range splitKeys(existing.GetAnnotations()[propagatedAnnotationsAnnotationKey]) {
+		if _, ok := desired.GetAnnotations()[key]; !ok {
+			annotations = append(annotations, key)
+		}
+	}
+	// the bookkeeping annotations themselves go away once nothing is propagated
+	for _, key := range []string{propagatedLabelsAnnotationKey, propagatedAnnotationsAnnotationKey} {
+		_, has := existing.GetAnnotations()[key]
+		_, want := desired.GetAnnotations()[key]
+		if has && !want {
+			annotations = append(annotations, key)
+		}
+	}
+	return labels, annotations
+}
+
+func splitKeys(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
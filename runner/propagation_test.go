@@ -0,0 +1,128 @@
+package runner_test
+
+import (
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPropagation_Prefix(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.PropagationPolicy = runner.PropagatePrefix
+	config.PropagatePrefixes = []string{"app.kubernetes.io/", "team"}
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{
+		Name:      "storeconfig-global",
+		Namespace: "myapp",
+		UID:       "1234",
+		Labels: map[string]string{
+			"app.kubernetes.io/part-of": "store",
+			"team":                      "payments",
+			"internal":                  "true",
+		},
+		Annotations: map[string]string{
+			"MakeGlobal":                  "true",
+			"MakeGlobalNamespaceSelector": "team",
+			"team/owner":                  "alice",
+			"checksum/config":             "1234",
+		},
+	}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.CreateConfigMap("default", global)
+	require.NoError(err)
+
+	created, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(map[string]string{
+		"CreatedBy":                 "k8s-global-objects",
+		"app.kubernetes.io/part-of": "store",
+		"team":                      "payments",
+	}, created.Labels)
+	require.Equal("alice", created.Annotations["team/owner"])
+	require.NotContains(created.Annotations, "checksum/config")
+	// a copy never becomes a global object
+	require.NotContains(created.Annotations, "MakeGlobal")
+	require.NotContains(created.Annotations, "MakeGlobalNamespaceSelector")
+	require.Equal("myapp", created.Annotations["GlobalSourceNamespace"])
+
+	// labels dropped from the global object are removed, labels other controllers added are kept
+	created.Labels["other"] = "controller"
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("default").Update(created)
+	delete(global.Labels, "team")
+	delete(global.Annotations, "team/owner")
+
+	configMapMaps := map[string]*runner.NamespaceConfigMaps{
+		"default": {Configmaps: []v1.ConfigMap{*created}},
+	}
+	err = runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+
+	updated, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(map[string]string{
+		"CreatedBy":                 "k8s-global-objects",
+		"app.kubernetes.io/part-of": "store",
+		"other":                     "controller",
+	}, updated.Labels)
+	require.NotContains(updated.Annotations, "team/owner")
+	require.NotContains(updated.Annotations, "GlobalPropagatedAnnotations")
+}
+
+func TestPropagation_All(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.PropagationPolicy = runner.PropagateAll
+
+	global := secret
+	global.ObjectMeta = metav1.ObjectMeta{
+		Name:        "registry-global",
+		Namespace:   "myapp",
+		Labels:      map[string]string{"app.kubernetes.io/part-of": "store", "CreatedBy": "someone"},
+		Annotations: map[string]string{"MakeGlobal": "true", "checksum/config": "1234"},
+	}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.CreateSecret("default", global)
+	require.NoError(err)
+
+	created, err := config.Client.Clientset.CoreV1().Secrets("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal("store", created.Labels["app.kubernetes.io/part-of"])
+	require.Equal("k8s-global-objects", created.Labels["CreatedBy"])
+	require.Equal("1234", created.Annotations["checksum/config"])
+	require.NotContains(created.Annotations, "MakeGlobal")
+}
+
+func TestPropagation_Init_NoPrefixes(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.PropagationPolicy = runner.PropagatePrefix
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	require.Error(runr.Init())
+}
@@ -0,0 +1,141 @@
+package runner
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// ParseGroupVersionResource parses group/version/resource, or version/resource for the core group
+func ParseGroupVersionResource(value string) (schema.GroupVersionResource, error) {
+	parts := strings.Split(value, "/")
+	for _, part := range parts {
+		if part == "" {
+			return schema.GroupVersionResource{}, fmt.Errorf("bad resource %q", value)
+		}
+	}
+
+	switch len(parts) {
+	case 2:
+		return schema.GroupVersionResource{Version: parts[0], Resource: parts[1]}, nil
+	case 3:
+		return schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}, nil
+	}
+	return schema.GroupVersionResource{}, fmt.Errorf("bad resource %q, expecting group/version/resource", value)
+}
+
+func (r *Runner) CreateResource(gvr schema.GroupVersionResource, namespace string, from unstructured.Unstructured) (err error) {
+	log.Debugf("Creating %v %v in namespace %v", gvr.Resource, copyName(&from), namespace)
+
+	object := r.createResourceObject(from)
+	object.SetNamespace(namespace)
+
+	if r.dryRun {
+		r.planChange("create", from.GetKind(), namespace, &from, nil, resourceContent(*object))
+		return nil
+	}
+
+	err = r.retryWrite("create", func() error {
+		_, err := r.client.Dynamic.Resource(gvr).Namespace(namespace).Create(object, metav1.CreateOptions{})
+		return err
+	})
+	recordWrite("create", from.GetKind(), err)
+	return err
+}
+
+func (r *Runner) UpdateResource(gvr schema.GroupVersionResource, namespace string, from unstructured.Unstructured) (err error) {
+	log.Debugf("Updating %v %v in namespace %v", gvr.Resource, copyName(&from), namespace)
+
+	object := r.createResourceObject(from)
+	object.SetNamespace(namespace)
+
+	if r.dryRun {
+		var before map[string]interface{}
+		if live, err := r.client.Dynamic.Resource(gvr).Namespace(namespace).Get(object.GetName(), metav1.GetOptions{}); err == nil {
+			before = resourceContent(*live)
+		}
+		r.planChange("update", from.GetKind(), namespace, &from, before, resourceContent(*object))
+		return nil
+	}
+
+	// updating the live object, a conflict fetches it again
+	err = r.retryWrite("update", func() error {
+		live, err := r.client.Dynamic.Resource(gvr).Namespace(namespace).Get(object.GetName(), metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		// keeping what other controllers added
+		updated := live.DeepCopy()
+		mergeMetadata(updated, object)
+		for key := range resourceContent(*updated) {
+			delete(updated.Object, key)
+		}
+		for key, value := range object.Object {
+			if key != "metadata" {
+				updated.Object[key] = value
+			}
+		}
+		_, err = r.client.Dynamic.Resource(gvr).Namespace(namespace).Update(updated, metav1.UpdateOptions{})
+		return err
+	})
+	recordWrite("update", from.GetKind(), err)
+	return err
+}
+
+func (r *Runner) DeleteResource(gvr schema.GroupVersionResource, namespace string, from unstructured.Unstructured) (err error) {
+	log.Debugf("Removing %v %v from namespace %v", gvr.Resource, copyName(&from), namespace)
+	if r.dryRun {
+		r.planChange("delete", from.GetKind(), namespace, &from, resourceContent(from), nil)
+		return nil
+	}
+	err = r.retryWrite("delete", func() error {
+		return r.client.Dynamic.Resource(gvr).Namespace(namespace).Delete(copyName(&from), &metav1.DeleteOptions{})
+	})
+	recordWrite("delete", from.GetKind(), err)
+	return err
+}
+
+// controllerFields are the top level fields controllers fill in for every namespace,
+// copies do not get them from the global object and keep their own
+var controllerFields = map[schema.GroupKind][]string{
+	// the token controller lists the token Secrets of the namespace
+	{Kind: "ServiceAccount"}: {"secrets"},
+}
+
+// resourceContent returns everything in the object but its metadata, status and controller fields
+func resourceContent(from unstructured.Unstructured) map[string]interface{} {
+	skip := make(map[string]bool)
+	for _, key := range controllerFields[from.GroupVersionKind().GroupKind()] {
+		skip[key] = true
+	}
+
+	content := make(map[string]interface{})
+	for key, value := range from.Object {
+		switch key {
+		case "apiVersion", "kind", "metadata", "status":
+			continue
+		}
+		if skip[key] {
+			continue
+		}
+		content[key] = value
+	}
+	return content
+}
+
+func (r *Runner) createResourceObject(from unstructured.Unstructured) *unstructured.Unstructured {
+	object := &unstructured.Unstructured{
+		Object: runtime.DeepCopyJSON(resourceContent(from)),
+	}
+	object.SetAPIVersion(from.GetAPIVersion())
+	object.SetKind(from.GetKind())
+	object.SetName(copyName(&from))
+	object.SetLabels(r.copyLabels(&from))
+	object.SetAnnotations(r.copyAnnotations(&from))
+	return object
+}
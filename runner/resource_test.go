@@ -0,0 +1,168 @@
+package runner_test
+
+import (
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestRunner_ParseGroupVersionResource(t *testing.T) {
+	require := require.New(t)
+
+	tests := []struct {
+		value string
+		gvr   schema.GroupVersionResource
+		fail  bool
+	}{
+		{value: "v1/limitranges", gvr: schema.GroupVersionResource{Version: "v1", Resource: "limitranges"}},
+		{value: "networking.k8s.io/v1/networkpolicies", gvr: schema.GroupVersionResource{Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"}},
+		{value: "limitranges", fail: true},
+		{value: "a/b/c/d", fail: true},
+		{value: "/v1/limitranges", fail: true},
+	}
+
+	for _, tt := range tests {
+		gvr, err := runner.ParseGroupVersionResource(tt.value)
+		if tt.fail {
+			require.Error(err, tt.value)
+			continue
+		}
+		require.NoError(err, tt.value)
+		require.Equal(tt.gvr, gvr)
+	}
+}
+
+func TestRunner_CreateResource(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_dynamic_client(fake_simple_client())
+	config.Debug = true
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	from := limitRange("myapp", "limits", "1")
+	err := runr.CreateResource(limitRangeResource, "default", *from)
+	require.NoError(err)
+
+	objects, err := config.Client.Dynamic.Resource(limitRangeResource).Namespace("default").List(metav1.ListOptions{})
+	require.NoError(err)
+	require.Len(objects.Items, 1)
+	require.Equal("default", objects.Items[0].GetNamespace())
+	require.Equal(map[string]string{"CreatedBy": "k8s-global-objects"}, objects.Items[0].GetLabels())
+	require.Equal(from.Object["spec"], objects.Items[0].Object["spec"])
+
+	err = runr.CreateResource(limitRangeResource, "default", *from)
+	require.Error(err)
+}
+
+func TestRunner_UpdateResource(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_dynamic_client(fake_simple_client(), limitRange("default", "limits", "1"))
+	config.Debug = true
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	from := limitRange("myapp", "limits", "2")
+	err := runr.UpdateResource(limitRangeResource, "default", *from)
+	require.NoError(err)
+
+	object, err := config.Client.Dynamic.Resource(limitRangeResource).Namespace("default").Get("limits", metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(from.Object["spec"], object.Object["spec"])
+}
+
+func TestRunner_DeleteResource(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_dynamic_client(fake_simple_client(), limitRange("default", "limits", "1"))
+	config.Debug = true
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	from := limitRange("myapp", "limits", "1")
+	err := runr.DeleteResource(limitRangeResource, "default", *from)
+	require.NoError(err)
+
+	err = runr.DeleteResource(limitRangeResource, "default", *from)
+	require.Error(err)
+}
+
+func serviceAccount(namespace string, name string, token string, pullSecret string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ServiceAccount",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+		},
+		"secrets":          []interface{}{map[string]interface{}{"name": token}},
+		"imagePullSecrets": []interface{}{map[string]interface{}{"name": pullSecret}},
+	}}
+}
+
+func TestRunner_ServiceAccountTokens(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	serviceAccounts := schema.GroupVersionResource{Version: "v1", Resource: "serviceaccounts"}
+	global := serviceAccount("myapp", "deployer", "deployer-token-myapp", "registry")
+	global.SetAnnotations(map[string]string{"MakeGlobal": "true"})
+	existing := serviceAccount("default", "deployer", "deployer-token-default", "registry")
+	existing.SetLabels(map[string]string{"CreatedBy": "k8s-global-objects"})
+	existing.SetAnnotations(map[string]string{"GlobalSourceNamespace": "myapp", "GlobalSourceName": "deployer", "GlobalSourceUID": ""})
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_dynamic_client(fake_simple_client(), existing)
+	config.Debug = true
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	// the tokens of the namespace are not drift
+	fakeDynamic := config.Client.Dynamic.(*dynamicfake.FakeDynamicClient)
+	fakeDynamic.ClearActions()
+	resourceMaps := map[string]*runner.NamespaceResources{
+		"default": {Objects: []unstructured.Unstructured{*existing}},
+	}
+	err := runr.AddAnnotatedResource(serviceAccounts, resourceMaps, "default", *global)
+	require.NoError(err)
+	for _, action := range fakeDynamic.Actions() {
+		require.NotEqual("update", action.GetVerb())
+	}
+
+	// updates keep them
+	global.Object["imagePullSecrets"] = []interface{}{map[string]interface{}{"name": "mirror"}}
+	err = runr.AddAnnotatedResource(serviceAccounts, resourceMaps, "default", *global)
+	require.NoError(err)
+	updated, err := config.Client.Dynamic.Resource(serviceAccounts).Namespace("default").Get("deployer", metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(global.Object["imagePullSecrets"], updated.Object["imagePullSecrets"])
+	require.Equal(existing.Object["secrets"], updated.Object["secrets"])
+
+	// new copies do not reference the tokens of the global object
+	err = runr.CreateResource(serviceAccounts, appNamespace, *global)
+	require.NoError(err)
+	created, err := config.Client.Dynamic.Resource(serviceAccounts).Namespace(appNamespace).Get("deployer", metav1.GetOptions{})
+	require.NoError(err)
+	require.NotContains(created.Object, "secrets")
+}
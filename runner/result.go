@@ -0,0 +1,51 @@
+package runner
+
+import (
+	"fmt"
+	"sort"
+
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+)
+
+// SyncResult collects the errors of one sync by namespace so a failing namespace does not stop the others
+type SyncResult struct {
+	// Namespaces scanned
+	Namespaces int
+	// Errors by namespace
+	Errors map[string][]error
+}
+
+func newSyncResult() *SyncResult {
+	return &SyncResult{
+		Errors: make(map[string][]error),
+	}
+}
+
+// add records err against namespace, nil errors are ignored
+func (s *SyncResult) add(namespace string, err error) {
+	if err == nil {
+		return
+	}
+	s.Errors[namespace] = append(s.Errors[namespace], err)
+}
+
+// FailedNamespaces returns the sorted namespaces that had errors
+func (s *SyncResult) FailedNamespaces() []string {
+	namespaces := make([]string, 0, len(s.Errors))
+	for namespace := range s.Errors {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// Err aggregates all errors, nil when every namespace synced
+func (s *SyncResult) Err() error {
+	errs := make([]error, 0)
+	for _, namespace := range s.FailedNamespaces() {
+		for _, err := range s.Errors[namespace] {
+			errs = append(errs, fmt.Errorf("namespace %v: %v", namespace, err))
+		}
+	}
+	return utilerrors.NewAggregate(errs)
+}
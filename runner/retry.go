@@ -0,0 +1,84 @@
+package runner
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// Prefix of the keys queueing a sync of the copies in one namespace, used to retry its failed writes
+	namespaceKeyPrefix = "namespace/"
+)
+
+func namespaceKey(namespace string) string {
+	return namespaceKeyPrefix + namespace
+}
+
+// keyNamespace returns the namespace synced by a queue key, empty for a sync of every namespace
+func keyNamespace(key interface{}) string {
+	if name, ok := key.(string); ok && strings.HasPrefix(name, namespaceKeyPrefix) {
+		return strings.TrimPrefix(name, namespaceKeyPrefix)
+	}
+	return ""
+}
+
+// retryWrite calls write again right away while it conflicts, write fetches the live object on every attempt.
+// Any other failure is returned, the namespace is queued again with backoff instead of the sync worker sleeping
+func (r *Runner) retryWrite(verb string, write func() error) error {
+	err := write()
+	for attempt := 0; attempt < r.maxRetries && apierrors.IsConflict(err); attempt++ {
+		log.WithError(err).Debugf("Retrying %v", verb)
+		writeRetries.WithLabelValues(verb).Inc()
+		err = write()
+	}
+	return err
+}
+
+// syncRateLimiter backs off every queue key exponentially, never waiting longer than the periodic resync
+func (r *Runner) syncRateLimiter() workqueue.RateLimiter {
+	maxDelay := r.runInterval
+	if maxDelay < r.retryBackoff {
+		maxDelay = r.retryBackoff
+	}
+	return workqueue.NewItemExponentialFailureRateLimiter(r.retryBackoff, maxDelay)
+}
+
+// requeueSync retries the sync of a key until the retry budget is spent,
+// after that the periodic resync picks it up
+func (r *Runner) requeueSync(key interface{}) {
+	if requeues := r.queue.NumRequeues(key); requeues >= r.maxRetries {
+		log.Warnf("Sync of %v failed %v times, waiting for the next resync in %v", key, requeues+1, r.runInterval)
+		r.queue.Forget(key)
+		return
+	}
+	r.queue.AddRateLimited(key)
+}
+
+// requeueFailed queues every namespace with errors on its own key, so one failing namespace
+// backs off without syncing the others again. A sync failing as a whole, or reading a source
+// provider, is queued again under its own key
+func (r *Runner) requeueFailed(key interface{}, result *SyncResult, err error) {
+	if err != nil || r.providerFailed(result) {
+		r.requeueSync(key)
+		return
+	}
+	if len(result.Errors[keyNamespace(key)]) == 0 {
+		r.queue.Forget(key)
+	}
+	for _, namespace := range result.FailedNamespaces() {
+		r.requeueSync(namespaceKey(namespace))
+	}
+}
+
+// providerFailed reports if a source provider had errors, they are recorded under its name
+func (r *Runner) providerFailed(result *SyncResult) bool {
+	for namespace := range result.Errors {
+		if r.externalSource(namespace) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package runner_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func retryConfig() runner.Config {
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Debug = true
+	config.MaxRetries = 3
+	config.RetryBackoff = 1 * time.Millisecond
+	return config
+}
+
+func TestRetry_UpdateConflict(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := retryConfig()
+
+	existing := configmap
+	existing.ObjectMeta.Name = "storeconfig-global"
+	existing.ObjectMeta.ResourceVersion = "7"
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("default").Create(&existing)
+
+	// conflicting twice, then accepting the update
+	updates := make([]string, 0)
+	config.Client.Clientset.(*fake.Clientset).Fake.PrependReactor("update", "configmaps", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		updated := action.(k8stesting.UpdateAction).GetObject().(*v1.ConfigMap)
+		updates = append(updates, updated.ResourceVersion)
+		if len(updates) <= 2 {
+			return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, updated.Name, errors.New("object was modified"))
+		}
+		return false, nil, nil
+	})
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	global := existing
+	global.ObjectMeta.Namespace = "myapp"
+	global.Data = map[string]string{"updateKey": "updateData"}
+	err := runr.UpdateConfigMap("default", global)
+	require.NoError(err)
+
+	// every attempt used the live resourceVersion
+	require.Equal([]string{"7", "7", "7"}, updates)
+
+	updated, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(global.Data, updated.Data)
+}
+
+func TestRetry_Budget(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := retryConfig()
+
+	existing := secret
+	existing.ObjectMeta.Name = "storeconfig-global"
+	_, _ = config.Client.Clientset.CoreV1().Secrets("default").Create(&existing)
+
+	// conflicting on every attempt
+	attempts := 0
+	config.Client.Clientset.(*fake.Clientset).Fake.PrependReactor("update", "secrets", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		attempts++
+		return true, nil, apierrors.NewConflict(schema.GroupResource{Resource: "secrets"}, existing.Name, errors.New("object was modified"))
+	})
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	global := existing
+	global.ObjectMeta.Namespace = "myapp"
+	err := runr.UpdateSecret("default", global)
+	require.True(apierrors.IsConflict(err))
+	require.Equal(config.MaxRetries+1, attempts)
+}
+
+func TestRetry_ThrottledNotRetriedInline(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := retryConfig()
+
+	// throttled on every attempt
+	attempts := 0
+	config.Client.Clientset.(*fake.Clientset).Fake.PrependReactor("create", "secrets", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		attempts++
+		return true, nil, apierrors.NewTooManyRequests("slow down", 0)
+	})
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	// the namespace is queued again with backoff, the write does not sleep
+	err := runr.CreateSecret("default", secret)
+	require.True(apierrors.IsTooManyRequests(err))
+	require.Equal(1, attempts)
+}
+
+func TestRetry_OutageDoesNotWedgeSync(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := retryConfig()
+	config.Once = true
+	config.RunInterval = 100 * time.Millisecond
+	// enough retries to wedge the sync worker for hours
+	config.MaxRetries = 30
+	config.RetryBackoff = 10 * time.Millisecond
+
+	annotatedConfigMap := configmap
+	annotatedConfigMap.ObjectMeta.Name = "storeconfig-global"
+	annotatedConfigMap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&annotatedConfigMap)
+
+	// failing cluster wide
+	config.Client.Clientset.(*fake.Clientset).Fake.PrependReactor("create", "configmaps", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, nil, apierrors.NewInternalError(errors.New("etcd unavailable"))
+	})
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	started := time.Now()
+	err := runr.Start()
+	require.Error(err)
+	require.True(time.Since(started) < 3*config.RunInterval, "sync took %v", time.Since(started))
+}
+
+func TestRetry_NotRetryable(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := retryConfig()
+
+	attempts := 0
+	config.Client.Clientset.(*fake.Clientset).Fake.PrependReactor("delete", "configmaps", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		attempts++
+		return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "configmaps"}, "configmap", errors.New("denied"))
+	})
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.DeleteConfigMap("default", configmap)
+	require.True(apierrors.IsForbidden(err))
+	require.Equal(1, attempts)
+}
+
+func TestRetry_Start_RequeuesFailedSync(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := retryConfig()
+	config.RunInterval = 1 * time.Hour
+
+	// admission webhook rejecting everything in the default namespace
+	var lock sync.Mutex
+	attempts := 0
+	config.Client.Clientset.(*fake.Clientset).Fake.PrependReactor("create", "configmaps", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		if action.GetNamespace() != "default" {
+			return false, nil, nil
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		attempts++
+		return true, nil, errors.New("denied by webhook")
+	})
+	countAttempts := func() int {
+		lock.Lock()
+		defer lock.Unlock()
+		return attempts
+	}
+
+	// create annotated configmap
+	annotatedConfigMap := configmap
+	annotatedConfigMap.ObjectMeta.Name = "storeconfig-global"
+	annotatedConfigMap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&annotatedConfigMap)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+
+	errs := make(chan error)
+	go func() {
+		errs <- runr.Start()
+	}()
+
+	// the failed namespace is retried long before the next resync, until the budget is spent
+	time.Sleep(300 * time.Millisecond)
+	retried := countAttempts()
+	require.True(retried >= config.MaxRetries+1, "expected at least %v attempts, got %v", config.MaxRetries+1, retried)
+	time.Sleep(300 * time.Millisecond)
+	require.Equal(retried, countAttempts())
+
+	runr.Close()
+	require.NoError(<-errs)
+}
@@ -2,17 +2,29 @@ package runner
 
 import (
 	"errors"
-	"strconv"
+	"fmt"
+	"path"
 	"sync"
 	"time"
 
 	log "github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
+	corelisters "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
 )
 
 type K8S struct {
 	Clientset kubernetes.Interface
+	Dynamic   dynamic.Interface
 }
 
 type Runner struct {
@@ -23,6 +35,67 @@ type Runner struct {
 	debug       bool
 	stopLock    sync.Mutex
 	stopped     bool
+
+	excludeNamespaces       []string
+	resources               []schema.GroupVersionResource
+	conflictPolicy          ConflictPolicy
+	orphanGracePeriod       time.Duration
+	maxRetries              int
+	retryBackoff            time.Duration
+	dryRun                  bool
+	serverSideApply         bool
+	compareFieldNames       []string
+	compareFields           map[string]bool
+	propagationPolicy       PropagationPolicy
+	propagatePrefixes       []string
+	sourceNamespaces        []string
+	sourceNamespaceSelector string
+	syncStatus              bool
+	// status of the global objects replicated by the current sync
+	sourceStatus map[string]*sourceStatus
+	// namespaces not allowed to publish global objects in the current sync
+	blockedSources      map[string]bool
+	duplicatePolicy     DuplicatePolicy
+	duplicateNamespaces []string
+	sourceProviders     []SourceProvider
+	recorder            record.EventRecorder
+	eventWatch          watch.Interface
+	// writes skipped by the current dry run sync
+	plan     *Plan
+	planLock sync.Mutex
+	lastPlan *Plan
+	// conflicts found during the current sync
+	conflicts int
+	// orphaned copies and when they were first seen
+	orphans     map[string]time.Time
+	orphansSeen map[string]bool
+
+	leaderElect    bool
+	leaseNamespace string
+	leaseName      string
+	leaseDuration  time.Duration
+	renewDeadline  time.Duration
+	retryPeriod    time.Duration
+	identity       string
+
+	// probe state
+	healthLock         sync.Mutex
+	accessValidated    bool
+	loopRunning        bool
+	lastHeartbeat      time.Time
+	standby            bool
+	lastSuccessfulSync time.Time
+
+	informerFactory informers.SharedInformerFactory
+	informersSynced []cache.InformerSynced
+	namespaceLister corelisters.NamespaceLister
+	configMapLister corelisters.ConfigMapLister
+	secretLister    corelisters.SecretLister
+	queue           workqueue.RateLimitingInterface
+	queueShutdown   sync.Once
+
+	dynamicInformerFactory dynamicinformer.DynamicSharedInformerFactory
+	resourceListers        map[schema.GroupVersionResource]cache.GenericLister
 }
 
 type Config struct {
@@ -30,12 +103,66 @@ type Config struct {
 	RunInterval time.Duration
 	Debug       bool
 	Once        bool
+	// Namespace names or glob patterns that never receive global objects
+	ExcludeNamespaces []string
+	// Additional namespaced resources replicated through the dynamic client
+	Resources []schema.GroupVersionResource
+	// What to do with same named objects the runner did not create
+	ConflictPolicy ConflictPolicy
+	// How long a copy stays orphaned before it is removed
+	OrphanGracePeriod time.Duration
+	// How often a conflicting write, a failed namespace or sync is retried, and the first requeue delay doubled on every attempt
+	MaxRetries   int
+	RetryBackoff time.Duration
+	// Plan the writes instead of making them
+	DryRun bool
+	// Server-side apply ConfigMap and Secret copies, needs a server supporting it
+	ServerSideApply bool
+	// Fields drift detection compares, every field of CompareFields when empty
+	CompareFields []string
+	// Which labels and annotations of a global object are copied, PropagatePrefixes is used by PropagatePrefix
+	PropagationPolicy PropagationPolicy
+	PropagatePrefixes []string
+	// Namespace names or glob patterns allowed to publish global objects, every namespace when empty
+	SourceNamespaces []string
+	// Label selector namespaces publishing global objects must match
+	SourceNamespaceSelector string
+	// Which global object is replicated when several namespaces publish the same name,
+	// DuplicateNamespaces lists the source namespaces in order of precedence for DuplicateNamespaces
+	DuplicatePolicy     DuplicatePolicy
+	DuplicateNamespaces []string
+	// Annotate global objects with the status of their copies after every sync
+	SyncStatus bool
+	// Global objects kept outside the cluster, replicated next to the annotated ones
+	SourceProviders []SourceProvider
+	// Records events, events go to the API server when nil
+	Recorder record.EventRecorder
+	// Only run the sync loop while holding the LeaseNamespace/LeaseName Lease
+	LeaderElect    bool
+	LeaseNamespace string
+	LeaseName      string
+	LeaseDuration  time.Duration
+	RenewDeadline  time.Duration
+	RetryPeriod    time.Duration
+	// Leader election identity of this replica, usually the pod name
+	Identity string
 }
 
 func DefaultConfig() *Config {
 	return &Config{
-		RunInterval: 30 * time.Second,
-		Client:      &K8S{},
+		RunInterval:       30 * time.Second,
+		Client:            &K8S{},
+		ConflictPolicy:    ConflictSkip,
+		PropagationPolicy: PropagateNever,
+		DuplicatePolicy:   DuplicateOldest,
+		SyncStatus:        true,
+		MaxRetries:        5,
+		RetryBackoff:      100 * time.Millisecond,
+		LeaseNamespace:    "k8s-global-objects",
+		LeaseName:         "k8s-global-objects",
+		LeaseDuration:     15 * time.Second,
+		RenewDeadline:     10 * time.Second,
+		RetryPeriod:       2 * time.Second,
 	}
 }
 
@@ -46,6 +173,55 @@ func NewRunner(config *Config) *Runner {
 		done:        make(chan struct{}),
 		debug:       config.Debug,
 		once:        config.Once,
+
+		excludeNamespaces:       config.ExcludeNamespaces,
+		resources:               config.Resources,
+		conflictPolicy:          config.ConflictPolicy,
+		orphanGracePeriod:       config.OrphanGracePeriod,
+		maxRetries:              config.MaxRetries,
+		retryBackoff:            config.RetryBackoff,
+		dryRun:                  config.DryRun,
+		serverSideApply:         config.ServerSideApply,
+		compareFieldNames:       config.CompareFields,
+		propagationPolicy:       config.PropagationPolicy,
+		propagatePrefixes:       config.PropagatePrefixes,
+		sourceNamespaces:        config.SourceNamespaces,
+		sourceNamespaceSelector: config.SourceNamespaceSelector,
+		blockedSources:          make(map[string]bool),
+		syncStatus:              config.SyncStatus,
+		sourceStatus:            make(map[string]*sourceStatus),
+		duplicatePolicy:         config.DuplicatePolicy,
+		duplicateNamespaces:     config.DuplicateNamespaces,
+		sourceProviders:         config.SourceProviders,
+		recorder:                config.Recorder,
+		plan:                    newPlan(),
+		orphans:                 make(map[string]time.Time),
+		orphansSeen:             make(map[string]bool),
+
+		leaderElect:    config.LeaderElect,
+		leaseNamespace: config.LeaseNamespace,
+		leaseName:      config.LeaseName,
+		leaseDuration:  config.LeaseDuration,
+		renewDeadline:  config.RenewDeadline,
+		retryPeriod:    config.RetryPeriod,
+		identity:       config.Identity,
+	}
+	if runner.conflictPolicy == "" {
+		runner.conflictPolicy = ConflictSkip
+	}
+	if runner.propagationPolicy == "" {
+		runner.propagationPolicy = PropagateNever
+	}
+	if runner.duplicatePolicy == "" {
+		runner.duplicatePolicy = DuplicateOldest
+	}
+	// bad fields are reported by Init
+	runner.compareFields, _ = parseCompareFields(config.CompareFields)
+	if runner.retryBackoff <= 0 {
+		runner.retryBackoff = 100 * time.Millisecond
+	}
+	if runner.maxRetries < 0 {
+		runner.maxRetries = 0
 	}
 
 	return runner
@@ -55,6 +231,73 @@ func (r *Runner) Init() error {
 	log.Debug("Initializing....")
 	defer log.Debug("Initializing Finished")
 
+	if _, err := ParseConflictPolicy(string(r.conflictPolicy)); err != nil {
+		log.WithError(err).Error("bad conflict policy")
+		return err
+	}
+
+	if _, err := ParsePropagationPolicy(string(r.propagationPolicy)); err != nil {
+		log.WithError(err).Error("bad propagation policy")
+		return err
+	}
+	if r.propagationPolicy == PropagatePrefix && len(r.propagatePrefixes) == 0 {
+		log.Error("Propagating by prefix requires at least one prefix")
+		return errors.New("no propagation prefixes")
+	}
+
+	if _, err := ParseDuplicatePolicy(string(r.duplicatePolicy)); err != nil {
+		log.WithError(err).Error("bad duplicate policy")
+		return err
+	}
+	if r.duplicatePolicy == DuplicateNamespaces && len(r.duplicateNamespaces) == 0 {
+		log.Error("Resolving duplicates by namespace requires at least one source namespace")
+		return errors.New("no duplicate source namespaces")
+	}
+
+	if _, err := parseCompareFields(r.compareFieldNames); err != nil {
+		log.WithError(err).Error("bad compare fields")
+		return err
+	}
+
+	for _, pattern := range r.excludeNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.WithError(err).Errorf("bad exclude namespace pattern %v", pattern)
+			return err
+		}
+	}
+	for _, pattern := range r.sourceNamespaces {
+		if _, err := path.Match(pattern, ""); err != nil {
+			log.WithError(err).Errorf("bad source namespace pattern %v", pattern)
+			return err
+		}
+	}
+	if _, err := labels.Parse(r.sourceNamespaceSelector); err != nil {
+		log.WithError(err).Errorf("bad source namespace selector %v", r.sourceNamespaceSelector)
+		return fmt.Errorf("bad source namespace selector %q: %v", r.sourceNamespaceSelector, err)
+	}
+
+	if len(r.resources) > 0 && r.client.Dynamic == nil {
+		log.Error("Replicating additional resources requires a dynamic client")
+		return errors.New("no dynamic client")
+	}
+	for _, gvr := range r.resources {
+		if gvr.Group == "" && (gvr.Resource == "configmaps" || gvr.Resource == "secrets") {
+			log.Errorf("Resource %v is always replicated and can not be configured", gvr.Resource)
+			return fmt.Errorf("resource %v can not be configured", gvr.Resource)
+		}
+	}
+
+	if r.leaderElect {
+		if r.leaseNamespace == "" || r.leaseName == "" || r.identity == "" {
+			log.Error("Leader election requires a lease namespace, lease name and identity")
+			return errors.New("incomplete leader election configuration")
+		}
+		if err := leaderElectionTimings(r.leaseDuration, r.renewDeadline, r.retryPeriod); err != nil {
+			log.WithError(err).Error("bad leader election configuration")
+			return err
+		}
+	}
+
 	// initial run validations
 	allowed, err := r.ValidateMyAccess()
 	if err != nil {
@@ -65,191 +308,209 @@ func (r *Runner) Init() error {
 		log.Error("App does not have enough permissions")
 		return errors.New("not enough permissions")
 	}
+	r.markAccessValidated()
+	r.startRecording()
 
 	log.Infof("Interval %v", r.runInterval)
 	log.Infof("Looking for K8S Objects with Annotation: %v", annotationKey)
+	log.Infof("Conflict policy: %v", r.conflictPolicy)
+	log.Infof("Orphaned copies grace period: %v", r.orphanGracePeriod)
+	log.Infof("Comparing fields: %v", r.compareFieldList())
+	if r.duplicatePolicy == DuplicateNamespaces {
+		log.Infof("Duplicate global objects resolved by source namespaces: %v", r.duplicateNamespaces)
+	} else {
+		log.Infof("Duplicate global objects resolved by: %v", r.duplicatePolicy)
+	}
+	if r.propagationPolicy == PropagatePrefix {
+		log.Infof("Propagating labels and annotations with prefixes: %v", r.propagatePrefixes)
+	} else {
+		log.Infof("Propagating labels and annotations: %v", r.propagationPolicy)
+	}
+	if r.dryRun {
+		log.Info("Dry run - planning changes without writing")
+	}
+	if r.serverSideApply {
+		log.Infof("Server-side applying ConfigMaps and Secrets as field manager %v", fieldManager)
+	}
+	log.Infof("Retrying conflicting writes, failed namespaces and syncs %v times starting at %v", r.maxRetries, r.retryBackoff)
+	for _, gvr := range r.resources {
+		log.Infof("Replicating additional resource: %v", gvr.String())
+	}
+	if len(r.excludeNamespaces) > 0 {
+		log.Infof("Excluding namespaces: %v", r.excludeNamespaces)
+	}
+	if len(r.sourceNamespaces) > 0 {
+		log.Infof("Only publishing global objects from namespaces: %v", r.sourceNamespaces)
+	}
+	if r.sourceNamespaceSelector != "" {
+		log.Infof("Only publishing global objects from namespaces matching: %v", r.sourceNamespaceSelector)
+	}
+	for _, provider := range r.sourceProviders {
+		log.Infof("Publishing global objects from %v", provider.Name())
+	}
+	if r.leaderElect {
+		log.Infof("Leader election on lease %v/%v as %v", r.leaseNamespace, r.leaseName, r.identity)
+	}
 	return nil
 }
 
 func (r *Runner) Start() error {
+	if r.leaderElect {
+		return r.runLeaderElection()
+	}
+	return r.runLoop()
+}
+
+// runLoop runs the sync loop until the runner is closed
+func (r *Runner) runLoop() error {
 
 	log.Debug("Starting runner")
 
-	ticker := time.NewTicker(r.runInterval)
-	defer ticker.Stop()
+	r.markLoopRunning(true)
+	defer r.markLoopRunning(false)
+
+	r.setupInformers()
+	defer r.shutdownQueue()
+
+	r.informerFactory.Start(r.done)
+	if r.dynamicInformerFactory != nil {
+		r.dynamicInformerFactory.Start(r.done)
+	}
+	log.Debug("Waiting for informer caches to sync")
+	if !cache.WaitForCacheSync(r.done, r.informersSynced...) {
+		if r.isStopped() {
+			return nil
+		}
+		return errors.New("failed waiting for informer caches to sync")
+	}
+
+	// periodic full resync, events enqueue work in between
+	go r.resyncLoop()
+
+	// initial sync
+	r.enqueueSync()
 
 	for {
-		select {
-		case <-ticker.C:
-			// run logic here
-			log.Info("Starting Global Object Sync")
-
-			// Filtered Holds objects that found the matching annotation
-			annotatedADDConfigMap := make([]v1.ConfigMap, 0)
-			annotatedREMOVEConfigMap := make([]v1.ConfigMap, 0)
-			annotatedADDSecret := make([]v1.Secret, 0)
-			annotatedREMOVESecret := make([]v1.Secret, 0)
-			// The following are the objects used for comparisons
-			configMapMaps := make(map[string]*NamespaceConfigMaps)
-			secretMaps := make(map[string]*NamepaceSecrets)
-
-			nsList, err := r.NamespacesList()
+		key, shutdown := r.queue.Get()
+		if shutdown {
+			return nil
+		}
+
+		started := time.Now()
+		namespace := keyNamespace(key)
+		result, err := r.runSync(namespace)
+		// retrying a namespace is not a sync of the cluster
+		if namespace == "" {
+			observeSync(started, result, err)
+			r.markSynced(err)
+		}
+		if err != nil {
+			log.WithError(err).Error("Sync failed")
+		}
+
+		if r.once {
+			r.queue.Done(key)
+			log.Debugf("RunOnce %v Exiting...", r.once)
+			r.Close()
 			if err != nil {
-				log.WithError(err).Error("list namespaces failed")
 				return err
 			}
+			return result.Err()
+		}
 
-			for _, namespace := range nsList.Items {
-				log.Debugf("Checking namespace %v", namespace.Name)
-
-				// Config Maps
-				cmList, err := r.ConfigMapList(namespace.Name)
-				if err != nil {
-					log.WithError(err).Errorf("list configmap failed for namespace %v", namespace.Name)
-					return err
-				}
-
-				// making map that will hold all Configmaps for the namespace
-				arrayConfigMaps := make([]v1.ConfigMap, 0)
-				for _, configmap := range cmList.Items {
-					// Populating array of Configmaps so we can use them later for comparisons
-					arrayConfigMaps = append(arrayConfigMaps, configmap)
-
-					chkGlobal, err := checkAnnotationKey(&configmap)
-					if err != nil {
-						log.WithError(err).Error("bad result from checkAnnotationKey")
-					}
-					// emoty result or non bool = no global object annotation
-					chkBool, err := strconv.ParseBool(chkGlobal)
-					if err != nil {
-						continue
-					}
-					// if false, will remove
-					if !chkBool {
-						log.Infof("Found %v %v annotation in %v", annotationKey, chkBool, configmap.SelfLink)
-						// add to remove filter
-						annotatedREMOVEConfigMap = append(annotatedREMOVEConfigMap, configmap)
-						continue
-					}
-					// else will add
-					log.Infof("Found %v %v annotation in %v", annotationKey, chkBool, configmap.SelfLink)
-					annotatedADDConfigMap = append(annotatedADDConfigMap, configmap)
-				}
-
-				// populating the map for this namespace with all Configmaps
-				configMapMaps[namespace.Name] = &NamespaceConfigMaps{
-					Configmaps: arrayConfigMaps,
-				}
-
-				// Secrets
-				sList, err := r.SecretList(namespace.Name)
-				if err != nil {
-					log.WithError(err).Error("list Secrets failed")
-					return err
-				}
-
-				// making map that will hold all Secrets for the namespace
-				arraySecrets := make([]v1.Secret, 0)
-				for _, secret := range sList.Items {
-					// Populating array of Secrets so we can use them later for comparisons
-					arraySecrets = append(arraySecrets, secret)
-
-					chkGlobal, err := checkAnnotationKey(&secret)
-					if err != nil {
-						log.WithError(err).Error("bad result from checkAnnotationKey")
-					}
-					// emoty result or non bool = no global object annotation
-					chkBool, err := strconv.ParseBool(chkGlobal)
-					if err != nil {
-						continue
-					}
-					// if false, will remove
-					if !chkBool {
-						log.Infof("Found %v %v annotation in %v", annotationKey, chkBool, secret.SelfLink)
-						// add to remove filter
-						annotatedREMOVESecret = append(annotatedREMOVESecret, secret)
-						continue
-					}
-					// else will add
-					log.Infof("Found %v %v annotation in %v", annotationKey, chkBool, secret.SelfLink)
-					annotatedADDSecret = append(annotatedADDSecret, secret)
-				}
-
-				// populating the map for this namespace with all Configmaps
-				secretMaps[namespace.Name] = &NamepaceSecrets{
-					Secrets: arraySecrets,
-				}
-			}
+		r.requeueFailed(key, result, err)
+		r.queue.Done(key)
+		if r.isStopped() {
+			return nil
+		}
+	}
+}
 
-			// work
-			for _, namespace := range nsList.Items {
-				// check if namespace needs the global object work
-				// Annotated ADD ConfigMap
-				for _, globalConfigMap := range annotatedADDConfigMap {
-					// skipping the namespace where the global object was found
-					if globalConfigMap.Namespace == namespace.Name {
-						continue
-					}
-					err := r.AddAnnotatedConfigMap(configMapMaps, namespace.Name, globalConfigMap)
-					if err != nil {
-						log.Error(err)
-						return err
-					}
-				}
-				// Annotated REMOVE ConfigMap
-				for _, globalConfigMap := range annotatedREMOVEConfigMap {
-					// skipping the namespace where the global object was found
-					if globalConfigMap.Namespace == namespace.Name {
-						continue
-					}
-					err := r.RemoveAnnotatedConfigMap(configMapMaps, namespace.Name, globalConfigMap)
-					if err != nil {
-						log.Error(err)
-						return err
-					}
-				}
-
-				// Annotated ADD Secret
-				for _, globalSecret := range annotatedADDSecret {
-					// skipping the namespace where the global object was found
-					if globalSecret.Namespace == namespace.Name {
-						continue
-					}
-					err := r.AddAnnotatedSecret(secretMaps, namespace.Name, globalSecret)
-					if err != nil {
-						log.Error(err)
-						return err
-					}
-				}
-				// Annotated REMOVE Secret
-				for _, globalSecret := range annotatedREMOVESecret {
-					// skipping the namespace where the global object was found
-					if globalSecret.Namespace == namespace.Name {
-						continue
-					}
-					err := r.RemoveAnnotatedSecret(secretMaps, namespace.Name, globalSecret)
-					if err != nil {
-						log.Error(err)
-						return err
-					}
-				}
-			}
+func (r *Runner) resyncLoop() {
+	ticker := time.NewTicker(r.runInterval)
+	defer ticker.Stop()
 
-			log.Info("Sync Finished")
-			if r.once {
-				log.Debugf("RunOnce %v Exiting...", r.once)
-				r.Close()
-			}
-			//if err != nil {
-			//	log.WithError(err)
-			//	break
-			//}
+	for {
+		select {
+		case <-ticker.C:
+			log.Debug("Periodic resync triggered")
+			r.enqueueSync()
 		case <-r.done:
-			return nil
+			r.shutdownQueue()
+			return
 		}
 	}
 }
 
+// runSync syncs the copies of every namespace, or only the ones of namespace when it is set
+func (r *Runner) runSync(namespace string) (*SyncResult, error) {
+	if namespace == "" {
+		log.Info("Starting Global Object Sync")
+	} else {
+		log.Infof("Starting Global Object Sync of namespace %v", namespace)
+	}
+	r.conflicts = 0
+	r.plan = newPlan()
+	r.sourceStatus = make(map[string]*sourceStatus)
+	result := newSyncResult()
+
+	nsList, err := r.cachedNamespaces()
+	if err != nil {
+		log.WithError(err).Error("list namespaces failed")
+		return result, err
+	}
+	result.Namespaces = len(nsList)
+	r.blockSources(nsList)
+
+	// every namespace is still scanned for global objects, only the targets are written
+	targets := nsList
+	if namespace != "" {
+		targets = make([]v1.Namespace, 0, 1)
+		for _, ns := range nsList {
+			if ns.Name == namespace {
+				targets = append(targets, ns)
+			}
+		}
+	}
+
+	// global objects kept outside the cluster, listed like namespaces
+	provided, providersFailed := r.readProviders(result)
+	kinds := []*syncKind{r.configMapKind(provided), r.secretKind(provided)}
+	for _, kind := range kinds {
+		kind.sourcesFailed = providersFailed
+	}
+	// additional resources
+	for _, gvr := range r.resources {
+		kinds = append(kinds, r.resourceKind(gvr))
+	}
+	for _, kind := range kinds {
+		r.syncObjects(kind, nsList, targets, result)
+	}
+
+	// orphans and statuses of the other namespaces were not looked at
+	if namespace == "" {
+		r.forgetOrphans()
+		r.writeSourceStatus()
+	}
+
+	if r.dryRun && namespace == "" {
+		log.Infof("Dry run - planned %v changes", len(r.plan.Changes))
+		r.planLock.Lock()
+		r.lastPlan = r.plan
+		r.planLock.Unlock()
+	}
+
+	if r.conflicts > 0 {
+		log.Warnf("Skipped %v objects not created by k8s-global-objects", r.conflicts)
+	}
+	if failed := result.FailedNamespaces(); len(failed) > 0 {
+		log.WithError(result.Err()).Errorf("Sync Finished with errors in namespaces %v", failed)
+		return result, nil
+	}
+	log.Info("Sync Finished")
+	return result, nil
+}
+
 func (r *Runner) Close() {
 	r.stopLock.Lock()
 	defer r.stopLock.Unlock()
@@ -260,4 +521,12 @@ func (r *Runner) Close() {
 
 	r.stopped = true
 	close(r.done)
+	r.stopRecording()
+}
+
+func (r *Runner) isStopped() bool {
+	r.stopLock.Lock()
+	defer r.stopLock.Unlock()
+
+	return r.stopped
 }
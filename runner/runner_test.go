@@ -1,6 +1,8 @@
 package runner_test
 
 import (
+	"errors"
+	"path"
 	"testing"
 
 	"time"
@@ -8,7 +10,14 @@ import (
 	"github.com/homedepot/k8s-global-objects/runner"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
 )
 
 func TestRunner_Constructor(t *testing.T) {
@@ -53,6 +62,8 @@ func TestRunner_Start(t *testing.T) {
 
 	err := runr.Start()
 	require.NoError(err)
+	// the loop exited after running once
+	require.Error(runr.Healthy())
 }
 
 func TestRunner_Start_w_ADD_AnnotatedObjects(t *testing.T) {
@@ -122,6 +133,9 @@ func TestRunner_Start_w_ADD_AnnotatedObjects(t *testing.T) {
 		require.Equal(sec.Data, annotatedSecret.Data)
 
 		if namespace.Name == "myapp" {
+			// the global objects only gained their sync status
+			delete(sec.Annotations, "GlobalSyncStatus")
+			delete(confMap.Annotations, "GlobalSyncStatus")
 			require.Equal(sec.Annotations, annotatedSecret.Annotations)
 			require.Equal(confMap.Annotations, annotatedConfigMap.Annotations)
 			continue
@@ -129,6 +143,14 @@ func TestRunner_Start_w_ADD_AnnotatedObjects(t *testing.T) {
 
 		require.NotEqual(sec.Annotations, annotatedSecret.Annotations)
 		require.NotEqual(confMap.Annotations, annotatedConfigMap.Annotations)
+
+		// copies trace back to the global object
+		require.Equal("myapp", confMap.Annotations["GlobalSourceNamespace"])
+		require.Equal(annotatedConfigMap.Name, confMap.Annotations["GlobalSourceName"])
+		require.Equal("k8s-global-objects", confMap.Labels["CreatedBy"])
+		require.Equal("myapp", sec.Annotations["GlobalSourceNamespace"])
+		require.Equal(annotatedSecret.Name, sec.Annotations["GlobalSourceName"])
+		require.Equal("k8s-global-objects", sec.Labels["CreatedBy"])
 	}
 
 	// triggering an to configmaps and secrets
@@ -140,3 +162,375 @@ func TestRunner_Start_w_ADD_AnnotatedObjects(t *testing.T) {
 	updatedS.Data = map[string][]byte{"updateKey": []byte("updateData")}
 	_, _ = config.Client.Clientset.CoreV1().Secrets("myapp").Create(&updatedS)
 }
+
+func TestRunner_Start_w_NamespaceErrors(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Debug = true
+	config.Once = true
+	config.RunInterval = 1 * time.Millisecond
+
+	// admission webhook rejecting everything in the default namespace
+	config.Client.Clientset.(*fake.Clientset).Fake.PrependReactor("create", "*", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		if action.GetNamespace() == "default" {
+			return true, nil, errors.New("denied by webhook")
+		}
+		return false, nil, nil
+	})
+
+	// create annotated configmap
+	annotatedConfigMap := configmap
+	annotatedConfigMap.ObjectMeta.Name = "storeconfig-global"
+	annotatedConfigMap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&annotatedConfigMap)
+
+	// create annotated secret
+	annotatedSecret := secret
+	annotatedSecret.ObjectMeta.Name = "mykey-global"
+	annotatedSecret.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	_, _ = config.Client.Clientset.CoreV1().Secrets("myapp").Create(&annotatedSecret)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	// the failing namespace is reported
+	err := runr.Start()
+	require.Error(err)
+	require.Contains(err.Error(), "namespace default: denied by webhook")
+	require.NotContains(err.Error(), "namespace "+appNamespace)
+
+	// every other namespace still got its copies
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Get(annotatedConfigMap.Name, metav1.GetOptions{})
+	require.NoError(err)
+	_, err = config.Client.Clientset.CoreV1().Secrets(appNamespace).Get(annotatedSecret.Name, metav1.GetOptions{})
+	require.NoError(err)
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get(annotatedConfigMap.Name, metav1.GetOptions{})
+	require.Error(err)
+}
+
+func TestRunner_Start_EventDriven(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Debug = true
+	// long interval so only events can trigger the sync
+	config.RunInterval = 1 * time.Hour
+
+	// create annotated configmap
+	annotatedConfigMap := configmap
+	annotatedConfigMap.ObjectMeta.Name = "storeconfig-global"
+	annotatedConfigMap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	annotatedConfigMap.ObjectMeta.SelfLink = "/some/path/myapp/" + annotatedConfigMap.ObjectMeta.Name
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&annotatedConfigMap)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- runr.Start()
+	}()
+
+	// new namespace should get the global object without waiting for the interval
+	_, err := config.Client.Clientset.CoreV1().Namespaces().Create(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "new-namespace"},
+	})
+	require.NoError(err)
+
+	found := false
+	for i := 0; i < 50; i++ {
+		_, err = config.Client.Clientset.CoreV1().ConfigMaps("new-namespace").Get(annotatedConfigMap.Name, metav1.GetOptions{})
+		if err == nil {
+			found = true
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	require.True(found)
+
+	runr.Close()
+	require.NoError(<-errCh)
+}
+
+func TestRunner_Start_w_NamespaceSelector(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Debug = true
+	config.Once = true
+	config.RunInterval = 1 * time.Millisecond
+
+	_, err := config.Client.Clientset.CoreV1().Namespaces().Create(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "team-a",
+			Labels: map[string]string{"team": "a"},
+		},
+	})
+	require.NoError(err)
+
+	// create annotated secret only for team namespaces
+	annotatedSecret := secret
+	annotatedSecret.ObjectMeta.Name = "registry-global"
+	annotatedSecret.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true", "MakeGlobalNamespaceSelector": "team"}
+	annotatedSecret.ObjectMeta.SelfLink = "/some/path/myapp/" + annotatedSecret.ObjectMeta.Name
+	_, _ = config.Client.Clientset.CoreV1().Secrets("myapp").Create(&annotatedSecret)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err = runr.Start()
+	require.NoError(err)
+
+	sec, err := config.Client.Clientset.CoreV1().Secrets("team-a").Get(annotatedSecret.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(sec.Data, annotatedSecret.Data)
+
+	_, err = config.Client.Clientset.CoreV1().Secrets("default").Get(annotatedSecret.Name, metav1.GetOptions{})
+	require.Error(err)
+}
+
+func TestRunner_Start_w_NamespaceSelector_Narrowed(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	// only copies are removed, even when other objects may be overwritten
+	config.ConflictPolicy = runner.ConflictOverwrite
+
+	_, err := config.Client.Clientset.CoreV1().Namespaces().Create(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   "team-a",
+			Labels: map[string]string{"team": "a"},
+		},
+	})
+	require.NoError(err)
+
+	annotatedSecret := secret
+	annotatedSecret.ObjectMeta.Name = "registry-global"
+	annotatedSecret.ObjectMeta.UID = "registry-global-uid"
+	annotatedSecret.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	annotatedSecret.ObjectMeta.SelfLink = "/some/path/myapp/" + annotatedSecret.ObjectMeta.Name
+	_, err = config.Client.Clientset.CoreV1().Secrets("myapp").Create(&annotatedSecret)
+	require.NoError(err)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+	require.NoError(runr.Start())
+
+	for _, namespace := range []string{"default", "team-a"} {
+		_, err = config.Client.Clientset.CoreV1().Secrets(namespace).Get(annotatedSecret.Name, metav1.GetOptions{})
+		require.NoError(err)
+	}
+	// replaced by an object the runner did not copy
+	require.NoError(config.Client.Clientset.CoreV1().Secrets(appNamespace).Delete(annotatedSecret.Name, &metav1.DeleteOptions{}))
+	localSecret := secret
+	localSecret.ObjectMeta = metav1.ObjectMeta{Name: annotatedSecret.Name, Namespace: appNamespace}
+	_, err = config.Client.Clientset.CoreV1().Secrets(appNamespace).Create(&localSecret)
+	require.NoError(err)
+
+	// narrowing the selector removes the copies from namespaces no longer selected
+	annotatedSecret.ObjectMeta.Annotations["MakeGlobalNamespaceSelector"] = "team"
+	_, err = config.Client.Clientset.CoreV1().Secrets("myapp").Update(&annotatedSecret)
+	require.NoError(err)
+
+	runr = runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+	require.NoError(runr.Start())
+
+	_, err = config.Client.Clientset.CoreV1().Secrets("team-a").Get(annotatedSecret.Name, metav1.GetOptions{})
+	require.NoError(err)
+	_, err = config.Client.Clientset.CoreV1().Secrets("default").Get(annotatedSecret.Name, metav1.GetOptions{})
+	require.True(apierrors.IsNotFound(err))
+	_, err = config.Client.Clientset.CoreV1().Secrets(appNamespace).Get(annotatedSecret.Name, metav1.GetOptions{})
+	require.NoError(err)
+}
+
+func TestRunner_Start_w_ExcludeNamespaces(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Debug = true
+	config.Once = true
+	config.RunInterval = 1 * time.Millisecond
+	config.ExcludeNamespaces = []string{"k8s-*"}
+
+	// opting out default namespace
+	_, err := config.Client.Clientset.CoreV1().Namespaces().Update(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "default",
+			Annotations: map[string]string{"MakeGlobalExclude": "true"},
+		},
+	})
+	require.NoError(err)
+
+	// create annotated configmap
+	annotatedConfigMap := configmap
+	annotatedConfigMap.ObjectMeta.Name = "storeconfig-global"
+	annotatedConfigMap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	annotatedConfigMap.ObjectMeta.SelfLink = "/some/path/myapp/" + annotatedConfigMap.ObjectMeta.Name
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&annotatedConfigMap)
+
+	// create remove annotated secret that has a copy in the excluded namespace
+	annotatedSecret := secret
+	annotatedSecret.ObjectMeta.Name = "mykey-global"
+	annotatedSecret.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "false"}
+	annotatedSecret.ObjectMeta.SelfLink = "/some/path/myapp/" + annotatedSecret.ObjectMeta.Name
+	_, _ = config.Client.Clientset.CoreV1().Secrets("myapp").Create(&annotatedSecret)
+	localSecret := secret
+	localSecret.ObjectMeta.Name = "mykey-global"
+	_, _ = config.Client.Clientset.CoreV1().Secrets(appNamespace).Create(&localSecret)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err = runr.Start()
+	require.NoError(err)
+
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get(annotatedConfigMap.Name, metav1.GetOptions{})
+	require.Error(err)
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps(appNamespace).Get(annotatedConfigMap.Name, metav1.GetOptions{})
+	require.Error(err)
+	_, err = config.Client.Clientset.CoreV1().Secrets(appNamespace).Get(localSecret.Name, metav1.GetOptions{})
+	require.NoError(err)
+}
+
+func TestRunner_ReadyAfterPartialSync(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	config.MaxRetries = 0
+	fakeClient := config.Client.Clientset.(*fake.Clientset)
+	fakeClient.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SelfSubjectAccessReview{
+			Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true},
+		}, nil
+	})
+
+	annotatedConfigMap := configmap
+	annotatedConfigMap.ObjectMeta.Name = "storeconfig-global"
+	annotatedConfigMap.ObjectMeta.Annotations = map[string]string{"MakeGlobal": "true"}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&annotatedConfigMap)
+
+	// admission webhook rejecting everything in the default namespace
+	fakeClient.PrependReactor("create", "configmaps", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		if action.GetNamespace() != "default" {
+			return false, nil, nil
+		}
+		return true, nil, errors.New("denied by webhook")
+	})
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	require.NoError(runr.Init())
+	require.Error(runr.Start())
+
+	// one broken namespace does not keep the runner unready
+	require.NoError(runr.Ready())
+}
+
+func TestRunner_Init_BadExcludePattern(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	k8s := fake_clientset()
+	k8s.Clientset.(*fake.Clientset).Fake.AddReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		return true, &authorizationv1.SelfSubjectAccessReview{
+			Status: authorizationv1.SubjectAccessReviewStatus{Allowed: true},
+		}, nil
+	})
+	config.Client = k8s
+	config.ExcludeNamespaces = []string{"kube-[system"}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Init()
+	require.Equal(path.ErrBadPattern, err)
+	// rejected before validating access
+	require.EqualError(runr.Ready(), "access not validated")
+}
+
+func TestRunner_Start_w_Resources(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	global := limitRange("myapp", "limits-global", "1")
+	global.SetAnnotations(map[string]string{"MakeGlobal": "true"})
+	// drifted copy
+	drifted := limitRangeCopy("default", "limits-global", "5")
+	// removed global object with a copy
+	removed := limitRange("myapp", "limits-removed", "1")
+	removed.SetAnnotations(map[string]string{"MakeGlobal": "false"})
+	removedCopy := limitRangeCopy("default", "limits-removed", "1")
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_dynamic_client(fake_simple_client(), global, drifted, removed, removedCopy)
+	config.Debug = true
+	config.Once = true
+	config.RunInterval = 1 * time.Millisecond
+	config.Resources = []schema.GroupVersionResource{limitRangeResource}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	for _, tt := range k8s_client {
+		object, err := config.Client.Dynamic.Resource(limitRangeResource).Namespace(tt.namespace).Get(global.GetName(), metav1.GetOptions{})
+		require.NoError(err)
+		require.Equal(global.Object["spec"], object.Object["spec"])
+	}
+
+	_, err = config.Client.Dynamic.Resource(limitRangeResource).Namespace("default").Get(removed.GetName(), metav1.GetOptions{})
+	require.Error(err)
+}
+
+func TestRunner_Init_Resources_NoDynamic(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Resources = []schema.GroupVersionResource{limitRangeResource}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Init()
+	require.Error(err)
+
+	config.Client = fake_dynamic_client(fake_simple_client())
+	config.Resources = []schema.GroupVersionResource{{Version: "v1", Resource: "configmaps"}}
+	runr = runner.NewRunner(&config)
+	defer runr.Close()
+
+	err = runr.Init()
+	require.Error(err)
+}
@@ -3,6 +3,7 @@ package runner
 import (
 	log "github.com/sirupsen/logrus"
 	"k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -16,45 +17,112 @@ func (r *Runner) SecretList(namespace string) (secrets *v1.SecretList, err error
 }
 
 func (r *Runner) CreateSecret(namespace string, from v1.Secret) (err error) {
-	log.Debugf("Creating Secret with name %v in namespace %v", from.Name, namespace)
+	log.Debugf("Creating Secret with name %v in namespace %v", copyName(&from), namespace)
 
-	secret := createSecretObject(from)
-	secret.ObjectMeta.Namespace = namespace
+	secret, err := r.createSecretObject(namespace, from)
+	if err != nil {
+		return err
+	}
 
-	_, err = r.client.Clientset.CoreV1().Secrets(namespace).Create(secret)
+	if r.dryRun {
+		r.planChange("create", "Secret", namespace, &from, nil, byteData(secret.Data))
+		return nil
+	}
+
+	// never applied, a forced apply would take over a same named object the informer cache has not seen yet
+	err = r.retryWrite("create", func() error {
+		_, err := r.client.Clientset.CoreV1().Secrets(namespace).Create(secret)
+		return err
+	})
+	recordWrite("create", "Secret", err)
 	return err
 }
 
 func (r *Runner) UpdateSecret(namespace string, from v1.Secret) (err error) {
-	log.Debugf("Updating Secret with name %v in namespace %v", from.Name, namespace)
+	log.Debugf("Updating Secret with name %v in namespace %v", copyName(&from), namespace)
 
-	secret := createSecretObject(from)
-	secret.ObjectMeta.Namespace = namespace
+	secret, err := r.createSecretObject(namespace, from)
+	if err != nil {
+		return err
+	}
+
+	if r.dryRun {
+		var before map[string]interface{}
+		if live, err := r.client.Clientset.CoreV1().Secrets(namespace).Get(secret.Name, metav1.GetOptions{}); err == nil {
+			before = byteData(live.Data)
+		}
+		r.planChange("update", "Secret", namespace, &from, before, byteData(secret.Data))
+		return nil
+	}
 
-	_, err = r.client.Clientset.CoreV1().Secrets(namespace).Update(secret)
+	// updating the live object, a conflict fetches it again
+	err = r.retryWrite("update", func() error {
+		live, err := r.client.Clientset.CoreV1().Secrets(namespace).Get(secret.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if live.Type != secret.Type {
+			return r.recreateSecret(namespace, live, secret)
+		}
+		if r.serverSideApply {
+			return r.applyCore("secrets", namespace, secret.Name, secret, &v1.Secret{})
+		}
+		// keeping what other controllers added
+		updated := live.DeepCopy()
+		mergeMetadata(updated, secret)
+		updated.Data = secret.Data
+		updated.Type = secret.Type
+		_, err = r.client.Clientset.CoreV1().Secrets(namespace).Update(updated)
+		return err
+	})
+	recordWrite("update", "Secret", err)
 	return err
 }
 
 func (r *Runner) DeleteSecret(namespace string, from v1.Secret) (err error) {
-	log.Debugf("Removing Secret %v from namespace %v", from.Name, namespace)
-	return r.client.Clientset.CoreV1().Secrets(namespace).Delete(from.Name, &metav1.DeleteOptions{})
+	log.Debugf("Removing Secret %v from namespace %v", copyName(&from), namespace)
+	if r.dryRun {
+		r.planChange("delete", "Secret", namespace, &from, byteData(from.Data), nil)
+		return nil
+	}
+	err = r.retryWrite("delete", func() error {
+		return r.client.Clientset.CoreV1().Secrets(namespace).Delete(copyName(&from), &metav1.DeleteOptions{})
+	})
+	recordWrite("delete", "Secret", err)
+	return err
 }
 
-func createSecretObject(from v1.Secret) *v1.Secret {
-	labels := map[string]string{
-		"CreatedBy": "k8s-global-objects",
+// recreateSecret replaces live with secret, the type of a Secret can not be updated
+func (r *Runner) recreateSecret(namespace string, live *v1.Secret, secret *v1.Secret) error {
+	log.Infof("Recreating Secret %v in namespace %v to change its type from %v to %v", secret.Name, namespace, live.Type, secret.Type)
+	err := r.client.Clientset.CoreV1().Secrets(namespace).Delete(live.Name, &metav1.DeleteOptions{
+		Preconditions: &metav1.Preconditions{UID: &live.UID},
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
 	}
+	_, err = r.client.Clientset.CoreV1().Secrets(namespace).Create(secret)
+	return err
+}
 
+// createSecretObject returns the copy of from for the namespace
+func (r *Runner) createSecretObject(namespace string, from v1.Secret) (*v1.Secret, error) {
+	data, err := r.renderSecretValues(&from, namespace, publishedBytes(&from, from.Data))
+	if err != nil {
+		return nil, err
+	}
 	return &v1.Secret{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Secret",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   from.Name,
-			Labels: labels,
+			Name:        copyName(&from),
+			Namespace:   namespace,
+			Labels:      r.copyLabels(&from),
+			Annotations: r.copyAnnotations(&from),
 		},
-		Data: from.Data,
+		Data: data,
 		Type: from.Type,
-	}
+	}, nil
 }
@@ -0,0 +1,220 @@
+package runner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// SourceProvider publishes global objects kept outside the cluster, next to the annotated objects found in namespaces
+type SourceProvider interface {
+	// Name identifies the provider, copies record it as the namespace of their global object
+	Name() string
+	// Sources returns the ConfigMaps and Secrets to replicate
+	Sources() ([]v1.ConfigMap, []v1.Secret, error)
+}
+
+// DirectorySource publishes every ConfigMap and Secret manifest found in a directory, such as a git-sync checkout
+type DirectorySource struct {
+	dir string
+}
+
+func NewDirectorySource(dir string) *DirectorySource {
+	return &DirectorySource{dir: dir}
+}
+
+// Name is never a valid namespace name, so it can not be mistaken for one
+func (d *DirectorySource) Name() string {
+	return "directory:" + d.dir
+}
+
+// Sources reads the .yaml, .yml and .json files of the directory and its subdirectories, skipping hidden ones
+func (d *DirectorySource) Sources() ([]v1.ConfigMap, []v1.Secret, error) {
+	// git-sync swaps a symlink to the checkout on every sync
+	root, err := filepath.EvalSymlinks(d.dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	configMaps := make([]v1.ConfigMap, 0)
+	secrets := make([]v1.Secret, 0)
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			// .git and the like
+			if path != root && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch filepath.Ext(path) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+
+		fileConfigMaps, fileSecrets, err := readManifests(path)
+		if err != nil {
+			return fmt.Errorf("reading %v: %v", path, err)
+		}
+		configMaps = append(configMaps, fileConfigMaps...)
+		secrets = append(secrets, fileSecrets...)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	// an empty or half synced checkout would remove every copy
+	if len(configMaps) == 0 && len(secrets) == 0 {
+		return nil, nil, fmt.Errorf("no ConfigMap or Secret found in %v", d.dir)
+	}
+	return configMaps, secrets, nil
+}
+
+// readManifests returns the ConfigMaps and Secrets of a manifest file, other kinds are ignored
+func readManifests(path string) ([]v1.ConfigMap, []v1.Secret, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	configMaps := make([]v1.ConfigMap, 0)
+	secrets := make([]v1.Secret, 0)
+	reader := yaml.NewYAMLReader(bufio.NewReader(file))
+	for {
+		document, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(bytes.TrimSpace(document)) == 0 {
+			continue
+		}
+		content, err := yaml.ToJSON(document)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var typeMeta metav1.TypeMeta
+		if err := json.Unmarshal(content, &typeMeta); err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case typeMeta.APIVersion == "v1" && typeMeta.Kind == "ConfigMap":
+			configMap := v1.ConfigMap{}
+			if err := json.Unmarshal(content, &configMap); err != nil {
+				return nil, nil, err
+			}
+			configMaps = append(configMaps, configMap)
+		case typeMeta.APIVersion == "v1" && typeMeta.Kind == "Secret":
+			secret := v1.Secret{}
+			if err := json.Unmarshal(content, &secret); err != nil {
+				return nil, nil, err
+			}
+			// the API server does the same on writes
+			for key, value := range secret.StringData {
+				if secret.Data == nil {
+					secret.Data = make(map[string][]byte)
+				}
+				secret.Data[key] = []byte(value)
+			}
+			secret.StringData = nil
+			// defaulted by the API server as well, copies are compared against it
+			if secret.Type == "" {
+				secret.Type = v1.SecretTypeOpaque
+			}
+			secrets = append(secrets, secret)
+		default:
+			log.Debugf("Ignoring %v %v in %v", typeMeta.APIVersion, typeMeta.Kind, path)
+		}
+	}
+	return configMaps, secrets, nil
+}
+
+// externalSource reports if the namespace of a global object is a source provider
+func (r *Runner) externalSource(namespace string) bool {
+	for _, provider := range r.sourceProviders {
+		if provider.Name() == namespace {
+			return true
+		}
+	}
+	return false
+}
+
+// providedObjects are the global objects a source provider published in this sync
+type providedObjects struct {
+	configMaps []v1.ConfigMap
+	secrets    []v1.Secret
+}
+
+// readProviders reads every source provider once per sync, returning the objects by provider name
+// and whether a provider failed to read
+func (r *Runner) readProviders(result *SyncResult) (map[string]*providedObjects, bool) {
+	provided := make(map[string]*providedObjects)
+	failed := false
+	for _, provider := range r.sourceProviders {
+		configMaps, secrets, err := providedSources(provider)
+		if err != nil {
+			log.WithError(err).Errorf("reading source %v failed", provider.Name())
+			result.add(provider.Name(), err)
+			failed = true
+			continue
+		}
+		log.Debugf("Found %v ConfigMaps and %v Secrets in %v", len(configMaps), len(secrets), provider.Name())
+		provided[provider.Name()] = &providedObjects{configMaps: configMaps, secrets: secrets}
+	}
+	return provided, failed
+}
+
+// providedNames returns the names of the providers that read, in their configured order
+func (r *Runner) providedNames(provided map[string]*providedObjects) []string {
+	names := make([]string, 0, len(provided))
+	for _, provider := range r.sourceProviders {
+		if _, ok := provided[provider.Name()]; ok {
+			names = append(names, provider.Name())
+		}
+	}
+	return names
+}
+
+// providedSources returns the global objects of the provider, recording the provider as their namespace
+func providedSources(provider SourceProvider) ([]v1.ConfigMap, []v1.Secret, error) {
+	configMaps, secrets, err := provider.Sources()
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range configMaps {
+		providedObject(provider, &configMaps[i])
+	}
+	for i := range secrets {
+		providedObject(provider, &secrets[i])
+	}
+	return configMaps, secrets, nil
+}
+
+func providedObject(provider SourceProvider, object metav1.Object) {
+	object.SetNamespace(provider.Name())
+	object.SetSelfLink(provider.Name() + "/" + object.GetName())
+	// every provided object is global, the annotation also keeps its copies from being orphaned
+	annotations := object.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[annotationKey] = "true"
+	object.SetAnnotations(annotations)
+}
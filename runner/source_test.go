@@ -0,0 +1,142 @@
+package runner_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const sourceManifests = `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: storeconfig-global
+data:
+  url: https://store.example.com
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: storecreds-global
+  annotations:
+    MakeGlobalNamespaceSelector: team
+stringData:
+  user: reader
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: not-a-global-object
+`
+
+func writeSourceDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "sources")
+	require.NoError(t, err)
+
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "global.yaml"), []byte(sourceManifests), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "README.md"), []byte("# not a manifest"), 0644))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, ".git"), 0755))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, ".git", "config.yaml"), []byte("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: hidden\n"), 0644))
+	return dir
+}
+
+func TestSource_Directory(t *testing.T) {
+	require := require.New(t)
+
+	dir := writeSourceDir(t)
+	defer os.RemoveAll(dir)
+
+	// git-sync publishes the checkout through a symlink
+	link := dir + "-link"
+	require.NoError(os.Symlink(dir, link))
+	defer os.Remove(link)
+
+	source := runner.NewDirectorySource(link)
+	require.Equal("directory:"+link, source.Name())
+
+	configMaps, secrets, err := source.Sources()
+	require.NoError(err)
+	require.Len(configMaps, 1)
+	require.Equal("storeconfig-global", configMaps[0].Name)
+	require.Equal("https://store.example.com", configMaps[0].Data["url"])
+	require.Len(secrets, 1)
+	require.Equal([]byte("reader"), secrets[0].Data["user"])
+	require.Empty(secrets[0].StringData)
+	require.Equal(v1.SecretTypeOpaque, secrets[0].Type)
+
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("kind: [ConfigMap"), 0644))
+	_, _, err = source.Sources()
+	require.Error(err)
+
+	// a directory without objects is not read as having removed them all
+	empty, err := ioutil.TempDir("", "sources")
+	require.NoError(err)
+	defer os.RemoveAll(empty)
+	_, _, err = runner.NewDirectorySource(empty).Sources()
+	require.Error(err)
+}
+
+func TestSource_Start(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	dir := writeSourceDir(t)
+	defer os.RemoveAll(dir)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	config.SourceProviders = []runner.SourceProvider{runner.NewDirectorySource(dir)}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	for _, namespace := range []string{"default", "myapp", appNamespace} {
+		res, err := config.Client.Clientset.CoreV1().ConfigMaps(namespace).Get("storeconfig-global", metav1.GetOptions{})
+		require.NoError(err)
+		require.Equal("https://store.example.com", res.Data["url"])
+		require.Equal("directory:"+dir, res.Annotations["GlobalSourceNamespace"])
+	}
+	// no namespace has the team label
+	_, err = config.Client.Clientset.CoreV1().Secrets("default").Get("storecreds-global", metav1.GetOptions{})
+	require.Error(err)
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get("hidden", metav1.GetOptions{})
+	require.Error(err)
+
+	// an emptied directory keeps the copies
+	require.NoError(os.Rename(filepath.Join(dir, "global.yaml"), filepath.Join(dir, "global.yaml.moved")))
+	runr = runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err = runr.Start()
+	require.Error(err)
+
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get("storeconfig-global", metav1.GetOptions{})
+	require.NoError(err)
+
+	// a manifest removed from the directory removes its copies
+	manifests := strings.SplitN(sourceManifests, "---", 2)
+	require.NoError(ioutil.WriteFile(filepath.Join(dir, "global.yaml"), []byte(manifests[1]), 0644))
+	runr = runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err = runr.Start()
+	require.NoError(err)
+
+	_, err = config.Client.Clientset.CoreV1().ConfigMaps("default").Get("storeconfig-global", metav1.GetOptions{})
+	require.Error(err)
+}
@@ -0,0 +1,210 @@
+package runner
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// Annotation on a global object summarizing the last sync of its copies
+	syncStatusAnnotationKey = "GlobalSyncStatus"
+	// an unchanged status is written again after this many run intervals, so lastSync stays close to the last sync
+	statusRefreshIntervals = 10
+)
+
+var (
+	configMapsResource = schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	secretsResource    = schema.GroupVersionResource{Version: "v1", Resource: "secrets"}
+)
+
+// SyncStatus is the replication status written on a global object
+type SyncStatus struct {
+	// When the status was last written, an unchanged status is only refreshed every few run intervals
+	LastSync string `json:"lastSync"`
+	// Target namespaces selected by the global object
+	Targets int `json:"targets"`
+	// Target namespaces holding an up to date copy
+	InSync int `json:"inSync"`
+	// Reasons by namespace for the targets not in sync
+	Failed map[string]string `json:"failed,omitempty"`
+}
+
+type sourceStatus struct {
+	gvr       schema.GroupVersionResource
+	namespace string
+	name      string
+	status    SyncStatus
+	// the status annotation of the global object when the sync listed it
+	current string
+	// clear removes the annotation of a global object no longer replicated
+	clear bool
+}
+
+func statusKey(gvr schema.GroupVersionResource, object metav1.Object) string {
+	return gvr.Resource + "/" + object.GetNamespace() + "/" + object.GetName()
+}
+
+// trackSource starts the status of a global object replicated in this sync
+func (r *Runner) trackSource(gvr schema.GroupVersionResource, global metav1.Object) {
+	// there is no object in the cluster to annotate
+	if r.externalSource(global.GetNamespace()) {
+		return
+	}
+	r.sourceStatus[statusKey(gvr, global)] = &sourceStatus{
+		gvr:       gvr,
+		namespace: global.GetNamespace(),
+		name:      global.GetName(),
+		status:    SyncStatus{Failed: make(map[string]string)},
+		current:   global.GetAnnotations()[syncStatusAnnotationKey],
+	}
+}
+
+// trackTarget records the outcome of syncing a global object into a target namespace
+func (r *Runner) trackTarget(gvr schema.GroupVersionResource, global metav1.Object, namespace string, err error, conflict bool) {
+	source, ok := r.sourceStatus[statusKey(gvr, global)]
+	if !ok {
+		return
+	}
+	source.status.Targets++
+	switch {
+	case err != nil:
+		source.status.Failed[namespace] = err.Error()
+	case conflict:
+		source.status.Failed[namespace] = "conflict: same named object not created by k8s-global-objects"
+	default:
+		source.status.InSync++
+	}
+}
+
+// untrackSource clears the status of a global object skipped by this sync, such as a duplicate loser
+func (r *Runner) untrackSource(gvr schema.GroupVersionResource, global metav1.Object) {
+	if _, ok := global.GetAnnotations()[syncStatusAnnotationKey]; !ok || r.externalSource(global.GetNamespace()) {
+		return
+	}
+	r.sourceStatus[statusKey(gvr, global)] = &sourceStatus{
+		gvr:       gvr,
+		namespace: global.GetNamespace(),
+		name:      global.GetName(),
+		clear:     true,
+	}
+}
+
+// stale reports if the status differs from the one on the global object, or that one is older than refresh
+func (s *sourceStatus) stale(refresh time.Duration) bool {
+	current := SyncStatus{}
+	if err := json.Unmarshal([]byte(s.current), &current); err != nil {
+		return true
+	}
+	written, err := time.Parse(time.RFC3339, current.LastSync)
+	if err != nil || time.Since(written) >= refresh {
+		return true
+	}
+	if current.Targets != s.status.Targets || current.InSync != s.status.InSync || len(current.Failed) != len(s.status.Failed) {
+		return true
+	}
+	for namespace, reason := range s.status.Failed {
+		if current.Failed[namespace] != reason {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSourceStatus annotates the global objects replicated in this sync whose status is stale
+func (r *Runner) writeSourceStatus() {
+	if !r.syncStatus || r.dryRun {
+		return
+	}
+	now := time.Now().UTC().Format(time.RFC3339)
+	refresh := statusRefreshIntervals * r.runInterval
+
+	keys := make([]string, 0, len(r.sourceStatus))
+	for key := range r.sourceStatus {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		source := r.sourceStatus[key]
+		if source.clear {
+			patch, _ := json.Marshal([]map[string]string{{
+				"op":   "remove",
+				"path": "/metadata/annotations/" + syncStatusAnnotationKey,
+			}})
+			err := r.patchSource(source, patch)
+			recordWrite("patch", source.gvr.Resource, err)
+			if err != nil {
+				log.WithError(err).Warnf("Failed clearing the status of %v", key)
+			}
+			continue
+		}
+		// patching every global object on every sync would only move lastSync
+		if !source.stale(refresh) {
+			log.Debugf("Status of %v unchanged", key)
+			continue
+		}
+		source.status.LastSync = now
+		value, err := json.Marshal(source.status)
+		if err != nil {
+			log.WithError(err).Errorf("Failed encoding the status of %v", key)
+			continue
+		}
+
+		// JSON patch works for every resource, annotations are always there as the global object is annotated
+		patch, _ := json.Marshal([]map[string]string{{
+			"op":    "add",
+			"path":  "/metadata/annotations/" + syncStatusAnnotationKey,
+			"value": string(value),
+		}})
+		err = r.patchSource(source, patch)
+		recordWrite("patch", source.gvr.Resource, err)
+		if err != nil {
+			// the status is informational, the next sync writes it again
+			log.WithError(err).Warnf("Failed writing the status of %v", key)
+		}
+	}
+}
+
+func (r *Runner) patchSource(source *sourceStatus, patch []byte) error {
+	var err error
+	switch source.gvr {
+	case configMapsResource:
+		_, err = r.client.Clientset.CoreV1().ConfigMaps(source.namespace).Patch(source.name, types.JSONPatchType, patch)
+	case secretsResource:
+		_, err = r.client.Clientset.CoreV1().Secrets(source.namespace).Patch(source.name, types.JSONPatchType, patch)
+	default:
+		_, err = r.client.Dynamic.Resource(source.gvr).Namespace(source.namespace).Patch(source.name, types.JSONPatchType, patch, metav1.UpdateOptions{})
+	}
+	return err
+}
+
+// statusOnlyUpdate reports if an update changed nothing but the status annotation, such as the runner writing it
+func statusOnlyUpdate(oldObj interface{}, newObj interface{}) bool {
+	oldObject, okOld := oldObj.(runtime.Object)
+	newObject, okNew := newObj.(runtime.Object)
+	if !okOld || !okNew {
+		return false
+	}
+	oldCopy, newCopy := oldObject.DeepCopyObject(), newObject.DeepCopyObject()
+	for _, object := range []runtime.Object{oldCopy, newCopy} {
+		meta, ok := object.(metav1.Object)
+		if !ok {
+			return false
+		}
+		annotations := meta.GetAnnotations()
+		delete(annotations, syncStatusAnnotationKey)
+		if len(annotations) == 0 {
+			annotations = nil
+		}
+		meta.SetAnnotations(annotations)
+		meta.SetResourceVersion("")
+	}
+	return reflect.DeepEqual(oldCopy, newCopy)
+}
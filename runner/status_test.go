@@ -0,0 +1,172 @@
+package runner_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestStatus_SourceAnnotation(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	config.MaxRetries = 0
+	config.Client.Clientset.(*fake.Clientset).Fake.PrependReactor("create", "configmaps", func(action k8stesting.Action) (handled bool, ret runtime.Object, err error) {
+		if action.GetNamespace() == "default" {
+			return true, nil, errors.New("admission denied")
+		}
+		return false, nil, nil
+	})
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{Name: "storeconfig-global", Namespace: "myapp", Annotations: map[string]string{"MakeGlobal": "true"}}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&global)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.Error(err)
+
+	res, err := config.Client.Clientset.CoreV1().ConfigMaps("myapp").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal("true", res.Annotations["MakeGlobal"])
+
+	status := runner.SyncStatus{}
+	require.NoError(json.Unmarshal([]byte(res.Annotations["GlobalSyncStatus"]), &status))
+	require.NotEmpty(status.LastSync)
+	require.Equal(2, status.Targets)
+	require.Equal(1, status.InSync)
+	require.Equal(map[string]string{"default": "admission denied"}, status.Failed)
+}
+
+func TestStatus_Unchanged(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{Name: "storeconfig-global", Namespace: "myapp", Annotations: map[string]string{"MakeGlobal": "true"}}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&global)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+	require.NoError(runr.Start())
+
+	res, err := config.Client.Clientset.CoreV1().ConfigMaps("myapp").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Contains(res.Annotations, "GlobalSyncStatus")
+
+	// the next sync finds the same status and does not patch the global object
+	fakeClient := config.Client.Clientset.(*fake.Clientset)
+	fakeClient.ClearActions()
+	runr = runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+	require.NoError(runr.Start())
+
+	for _, action := range fakeClient.Actions() {
+		require.NotEqual("patch", action.GetVerb(), "unexpected patch of %v", action.GetResource())
+	}
+}
+
+func TestStatus_Disabled(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	config.SyncStatus = false
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{Name: "storeconfig-global", Namespace: "myapp", Annotations: map[string]string{"MakeGlobal": "true"}}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&global)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	res, err := config.Client.Clientset.CoreV1().ConfigMaps("myapp").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.NotContains(res.Annotations, "GlobalSyncStatus")
+}
+
+func TestStatus_Refresh(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+
+	// the same status, written long ago
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{Name: "storeconfig-global", Namespace: "myapp", Annotations: map[string]string{
+		"MakeGlobal":       "true",
+		"GlobalSyncStatus": `{"lastSync":"2019-01-22T23:20:52Z","targets":2,"inSync":2}`,
+	}}
+	_, _ = config.Client.Clientset.CoreV1().ConfigMaps("myapp").Create(&global)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+	require.NoError(runr.Start())
+
+	res, err := config.Client.Clientset.CoreV1().ConfigMaps("myapp").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	status := runner.SyncStatus{}
+	require.NoError(json.Unmarshal([]byte(res.Annotations["GlobalSyncStatus"]), &status))
+	require.NotEqual("2019-01-22T23:20:52Z", status.LastSync)
+	require.Equal(2, status.Targets)
+	require.Equal(2, status.InSync)
+}
+
+func TestStatus_DuplicateLoserCleared(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+	// the newer global object was replicated before the older one was published
+	_, newer := publishDuplicates(config.Client, map[string]string{"GlobalSyncStatus": `{"lastSync":"2019-01-22T23:20:52Z","targets":2,"inSync":2}`})
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+	require.NoError(runr.Start())
+
+	// the fake keeps map keys a JSON patch removes, so look at the patch itself
+	patched := false
+	for _, action := range config.Client.Clientset.(*fake.Clientset).Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok && patch.GetNamespace() == "myapp" && patch.GetName() == newer.Name {
+			require.JSONEq(`[{"op":"remove","path":"/metadata/annotations/GlobalSyncStatus"}]`, string(patch.GetPatch()))
+			patched = true
+		}
+	}
+	require.True(patched)
+
+	winner, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(newer.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Contains(winner.Annotations, "GlobalSyncStatus")
+}
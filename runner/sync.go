@@ -0,0 +1,303 @@
+package runner
+
+import (
+	"strconv"
+
+	log "github.com/sirupsen/logrus"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// syncKind describes how one kind of global object is listed, built and written, every kind shares the sync loop
+type syncKind struct {
+	kind string
+	gvr  schema.GroupVersionResource
+	// sources are the source providers listed like namespaces after them
+	sources []string
+	// sourcesFailed is set when a source provider failed to read, its copies can not be told apart from orphans
+	sourcesFailed bool
+	// objects by namespace, filled by the sync loop
+	objects map[string][]metav1.Object
+
+	// list returns the objects of a namespace
+	list func(namespace string) ([]metav1.Object, error)
+	// values returns the data values of a global object
+	values func(global metav1.Object) map[string]string
+	// build returns the copy of the global object the runner writes in the namespace
+	build func(namespace string, global metav1.Object) (metav1.Object, error)
+	// drift returns the fields of compare where the existing copy differs from the built one
+	drift  func(desired metav1.Object, existing metav1.Object, compare map[string]bool) []string
+	create func(namespace string, global metav1.Object) error
+	update func(namespace string, global metav1.Object) error
+	delete func(namespace string, object metav1.Object) error
+}
+
+// kindOf returns the kind of the object, the additional resources only know it from their objects
+func (k *syncKind) kindOf(object metav1.Object) string {
+	if typed, ok := object.(interface{ GetKind() string }); ok && k.kind == "" {
+		return typed.GetKind()
+	}
+	return k.kind
+}
+
+// object returns the listed object of the namespace with the name, nil when there is none
+func (k *syncKind) object(namespace string, name string) metav1.Object {
+	for _, object := range k.objects[namespace] {
+		if object.GetName() == name {
+			return object
+		}
+	}
+	return nil
+}
+
+// configMapKind replicates ConfigMaps, provided holds the ConfigMaps of the source providers
+func (r *Runner) configMapKind(provided map[string]*providedObjects) *syncKind {
+	return &syncKind{
+		kind:    "ConfigMap",
+		gvr:     configMapsResource,
+		sources: r.providedNames(provided),
+		objects: make(map[string][]metav1.Object),
+		list: func(namespace string) ([]metav1.Object, error) {
+			if source, ok := provided[namespace]; ok {
+				return configMapObjects(source.configMaps), nil
+			}
+			configMaps, err := r.cachedConfigMaps(namespace)
+			if err != nil {
+				return nil, err
+			}
+			return configMapObjects(configMaps), nil
+		},
+		values: func(global metav1.Object) map[string]string {
+			return global.(*v1.ConfigMap).Data
+		},
+		build: func(namespace string, global metav1.Object) (metav1.Object, error) {
+			return r.createConfigMapObject(namespace, *global.(*v1.ConfigMap))
+		},
+		drift: func(desired metav1.Object, existing metav1.Object, compare map[string]bool) []string {
+			return configMapDrift(desired.(*v1.ConfigMap), existing.(*v1.ConfigMap), compare)
+		},
+		create: func(namespace string, global metav1.Object) error {
+			return r.CreateConfigMap(namespace, *global.(*v1.ConfigMap))
+		},
+		update: func(namespace string, global metav1.Object) error {
+			return r.UpdateConfigMap(namespace, *global.(*v1.ConfigMap))
+		},
+		delete: func(namespace string, object metav1.Object) error {
+			return r.DeleteConfigMap(namespace, *object.(*v1.ConfigMap))
+		},
+	}
+}
+
+// secretKind replicates Secrets, provided holds the Secrets of the source providers
+func (r *Runner) secretKind(provided map[string]*providedObjects) *syncKind {
+	return &syncKind{
+		kind:    "Secret",
+		gvr:     secretsResource,
+		sources: r.providedNames(provided),
+		objects: make(map[string][]metav1.Object),
+		list: func(namespace string) ([]metav1.Object, error) {
+			if source, ok := provided[namespace]; ok {
+				return secretObjects(source.secrets), nil
+			}
+			secrets, err := r.cachedSecrets(namespace)
+			if err != nil {
+				return nil, err
+			}
+			return secretObjects(secrets), nil
+		},
+		values: func(global metav1.Object) map[string]string {
+			return secretStrings(global.(*v1.Secret).Data)
+		},
+		build: func(namespace string, global metav1.Object) (metav1.Object, error) {
+			return r.createSecretObject(namespace, *global.(*v1.Secret))
+		},
+		drift: func(desired metav1.Object, existing metav1.Object, compare map[string]bool) []string {
+			return secretDrift(desired.(*v1.Secret), existing.(*v1.Secret), compare)
+		},
+		create: func(namespace string, global metav1.Object) error {
+			return r.CreateSecret(namespace, *global.(*v1.Secret))
+		},
+		update: func(namespace string, global metav1.Object) error {
+			return r.UpdateSecret(namespace, *global.(*v1.Secret))
+		},
+		delete: func(namespace string, object metav1.Object) error {
+			return r.DeleteSecret(namespace, *object.(*v1.Secret))
+		},
+	}
+}
+
+// resourceKind replicates an additional resource through the dynamic client
+func (r *Runner) resourceKind(gvr schema.GroupVersionResource) *syncKind {
+	return &syncKind{
+		gvr:     gvr,
+		objects: make(map[string][]metav1.Object),
+		list: func(namespace string) ([]metav1.Object, error) {
+			resources, err := r.cachedResources(gvr, namespace)
+			if err != nil {
+				return nil, err
+			}
+			return resourceObjects(resources), nil
+		},
+		// templates only apply to data values
+		values: func(global metav1.Object) map[string]string {
+			return nil
+		},
+		build: func(namespace string, global metav1.Object) (metav1.Object, error) {
+			object := r.createResourceObject(*global.(*unstructured.Unstructured))
+			object.SetNamespace(namespace)
+			return object, nil
+		},
+		drift: func(desired metav1.Object, existing metav1.Object, compare map[string]bool) []string {
+			return resourceDrift(desired.(*unstructured.Unstructured), existing.(*unstructured.Unstructured), compare)
+		},
+		create: func(namespace string, global metav1.Object) error {
+			return r.CreateResource(gvr, namespace, *global.(*unstructured.Unstructured))
+		},
+		update: func(namespace string, global metav1.Object) error {
+			return r.UpdateResource(gvr, namespace, *global.(*unstructured.Unstructured))
+		},
+		delete: func(namespace string, object metav1.Object) error {
+			return r.DeleteResource(gvr, namespace, *object.(*unstructured.Unstructured))
+		},
+	}
+}
+
+func configMapObjects(configMaps []v1.ConfigMap) []metav1.Object {
+	objects := make([]metav1.Object, len(configMaps))
+	for i := range configMaps {
+		objects[i] = &configMaps[i]
+	}
+	return objects
+}
+
+func secretObjects(secrets []v1.Secret) []metav1.Object {
+	objects := make([]metav1.Object, len(secrets))
+	for i := range secrets {
+		objects[i] = &secrets[i]
+	}
+	return objects
+}
+
+func resourceObjects(resources []unstructured.Unstructured) []metav1.Object {
+	objects := make([]metav1.Object, len(resources))
+	for i := range resources {
+		objects[i] = &resources[i]
+	}
+	return objects
+}
+
+// syncObjects replicates the global objects of one kind found in nsList to the targets
+func (r *Runner) syncObjects(k *syncKind, nsList []v1.Namespace, targets []v1.Namespace, result *SyncResult) {
+	log.Debugf("Syncing %v", k.gvr.String())
+
+	// Filtered Holds objects that found the matching annotation
+	annotatedADD := make([]metav1.Object, 0)
+	annotatedREMOVE := make([]metav1.Object, 0)
+	// orphans can not be told apart from objects missing in a namespace that failed to list
+	listFailed := k.sourcesFailed
+
+	sources := make([]string, 0, len(nsList)+len(k.sources))
+	for _, namespace := range nsList {
+		sources = append(sources, namespace.Name)
+	}
+	for _, namespace := range append(sources, k.sources...) {
+		log.Debugf("Checking namespace %v", namespace)
+
+		objects, err := k.list(namespace)
+		if err != nil {
+			log.WithError(err).Errorf("list %v failed for namespace %v", k.gvr.Resource, namespace)
+			result.add(namespace, err)
+			listFailed = true
+			continue
+		}
+		k.objects[namespace] = objects
+
+		for _, object := range objects {
+			chkGlobal, err := checkAnnotationKey(object)
+			if err != nil {
+				log.WithError(err).Error("bad result from checkAnnotationKey")
+			}
+			// emoty result or non bool = no global object annotation
+			chkBool, err := strconv.ParseBool(chkGlobal)
+			if err != nil {
+				continue
+			}
+			// only allowed namespaces publish global objects
+			if r.blockedSources[namespace] {
+				r.reportBlockedSource(k.kindOf(object), object)
+				continue
+			}
+			// if false, will remove
+			if !chkBool {
+				log.Infof("Found %v %v annotation in %v", annotationKey, chkBool, object.GetSelfLink())
+				// add to remove filter
+				annotatedREMOVE = append(annotatedREMOVE, object)
+				continue
+			}
+			// else will add
+			log.Infof("Found %v %v annotation in %v", annotationKey, chkBool, object.GetSelfLink())
+			if err := checkGlobal(object, k.values(object)); err != nil {
+				log.WithError(err).Errorf("bad global object %v - skipping", object.GetSelfLink())
+				continue
+			}
+			annotatedADD = append(annotatedADD, object)
+		}
+	}
+
+	// one global object per name
+	annotatedADD, duplicates := r.dropDuplicates(k, annotatedADD)
+	for _, global := range annotatedADD {
+		r.trackSource(k.gvr, global)
+	}
+
+	// work
+	for _, namespace := range targets {
+		// skipping namespaces that opted out of global objects
+		if r.namespaceExcluded(namespace) {
+			continue
+		}
+		// skipping namespaces that failed to list
+		if _, ok := k.objects[namespace.Name]; !ok {
+			continue
+		}
+
+		// Annotated ADD
+		for _, global := range annotatedADD {
+			// skipping the namespace where the global object was found
+			if global.GetNamespace() == namespace.Name {
+				continue
+			}
+			// skipping namespaces not matching the namespace selector, removing the copies made before it changed
+			if !namespaceSelected(global, namespace) {
+				log.Debugf("Namespace %v not selected by %v", namespace.Name, global.GetSelfLink())
+				if existing := k.object(namespace.Name, copyName(global)); existing != nil && isCopyOf(existing, global) {
+					result.add(namespace.Name, r.removeAnnotated(k, namespace.Name, global))
+				}
+				continue
+			}
+			// skipping the namespace publishing a same named global object
+			if duplicates[duplicateKey(namespace.Name, copyName(global))] {
+				continue
+			}
+			conflicts := r.conflicts
+			err := r.addAnnotated(k, namespace.Name, global)
+			result.add(namespace.Name, err)
+			r.trackTarget(k.gvr, global, namespace.Name, err, r.conflicts > conflicts)
+		}
+		// Annotated REMOVE
+		for _, global := range annotatedREMOVE {
+			// skipping the namespace where the global object was found
+			if global.GetNamespace() == namespace.Name {
+				continue
+			}
+			result.add(namespace.Name, r.removeAnnotated(k, namespace.Name, global))
+		}
+
+		// copies whose global object is gone
+		if !listFailed {
+			result.add(namespace.Name, r.removeOrphaned(k, namespace.Name))
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"text/template"
+
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// Annotation rendering the data values of the global object as Go templates for every target namespace
+	templateAnnotationKey = "MakeGlobalTemplate"
+)
+
+// TemplateData is what the templated values of a global object can use
+type TemplateData struct {
+	// Name of the target namespace
+	Namespace string
+	// Labels of the target namespace
+	Labels map[string]string
+	// Annotations of the target namespace
+	Annotations map[string]string
+}
+
+// templated reports if the values of the global object are rendered per namespace
+func templated(object metav1.Object) bool {
+	enabled, err := strconv.ParseBool(object.GetAnnotations()[templateAnnotationKey])
+	return err == nil && enabled
+}
+
+func parseValue(key string, value string) (*template.Template, error) {
+	return template.New(key).Option("missingkey=error").Parse(value)
+}
+
+// checkTemplates parses the templated values, so a bad global object is skipped before reaching any namespace
+func checkTemplates(object metav1.Object, values map[string]string) error {
+	if !templated(object) {
+		return nil
+	}
+	for key, value := range values {
+		if _, err := parseValue(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderValues renders the values of a templated global object for the target namespace, others are returned as is
+func (r *Runner) renderValues(object metav1.Object, namespace string, values map[string]string) (map[string]string, error) {
+	if !templated(object) || len(values) == 0 {
+		return values, nil
+	}
+
+	target, err := r.targetNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+	data := TemplateData{
+		Namespace:   target.Name,
+		Labels:      target.Labels,
+		Annotations: target.Annotations,
+	}
+
+	rendered := make(map[string]string, len(values))
+	for key, value := range values {
+		tmpl, err := parseValue(key, value)
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			return nil, fmt.Errorf("rendering %v for namespace %v: %v", key, namespace, err)
+		}
+		rendered[key] = out.String()
+	}
+	return rendered, nil
+}
+
+// renderSecretValues renders the values of a templated global Secret for the target namespace
+func (r *Runner) renderSecretValues(object metav1.Object, namespace string, values map[string][]byte) (map[string][]byte, error) {
+	if !templated(object) {
+		return values, nil
+	}
+	rendered, err := r.renderValues(object, namespace, secretStrings(values))
+	if err != nil {
+		return nil, err
+	}
+	data := make(map[string][]byte, len(rendered))
+	for key, value := range rendered {
+		data[key] = []byte(value)
+	}
+	return data, nil
+}
+
+func secretStrings(values map[string][]byte) map[string]string {
+	strings := make(map[string]string, len(values))
+	for key, value := range values {
+		strings[key] = string(value)
+	}
+	return strings
+}
+
+// targetNamespace returns the namespace from the informer cache, or the API when the informers are not running
+func (r *Runner) targetNamespace(name string) (*v1.Namespace, error) {
+	if r.namespaceLister != nil {
+		return r.namespaceLister.Get(name)
+	}
+	return r.client.Clientset.CoreV1().Namespaces().Get(name, metav1.GetOptions{})
+}
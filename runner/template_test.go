@@ -0,0 +1,104 @@
+package runner_test
+
+import (
+	"testing"
+
+	"github.com/homedepot/k8s-global-objects/runner"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestTemplate_ConfigMap(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	_, err := config.Client.Clientset.CoreV1().Namespaces().Update(&v1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "default", Labels: map[string]string{"tier": "gold"}},
+	})
+	require.NoError(err)
+
+	global := configmap
+	global.ObjectMeta = metav1.ObjectMeta{
+		Name:        "storeconfig-global",
+		Namespace:   "myapp",
+		Annotations: map[string]string{"MakeGlobal": "true", "MakeGlobalTemplate": "true"},
+	}
+	global.Data = map[string]string{
+		"url":  "https://{{ .Namespace }}.svc.example.com",
+		"tier": "{{ .Labels.tier }}",
+	}
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err = runr.CreateConfigMap("default", global)
+	require.NoError(err)
+
+	created, err := config.Client.Clientset.CoreV1().ConfigMaps("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal(map[string]string{"url": "https://default.svc.example.com", "tier": "gold"}, created.Data)
+
+	// the rendered copy is not drift
+	fakeClient := config.Client.Clientset.(*fake.Clientset)
+	fakeClient.ClearActions()
+	configMapMaps := map[string]*runner.NamespaceConfigMaps{
+		"default": {Configmaps: []v1.ConfigMap{*created}},
+	}
+	err = runr.AddAnnotatedConfigMap(configMapMaps, "default", global)
+	require.NoError(err)
+	for _, action := range fakeClient.Actions() {
+		require.NotEqual("update", action.GetVerb())
+	}
+
+	// a namespace missing the label can not render the copy
+	err = runr.CreateConfigMap(appNamespace, global)
+	require.Error(err)
+}
+
+func TestTemplate_Secret(t *testing.T) {
+	require := require.New(t)
+	log.SetLevel(log.DebugLevel)
+
+	config := *runner.DefaultConfig()
+	config.Client = fake_simple_client()
+	config.Once = true
+
+	global := secret
+	global.ObjectMeta = metav1.ObjectMeta{
+		Name:        "storecreds-global",
+		Namespace:   "myapp",
+		Annotations: map[string]string{"MakeGlobal": "true", "MakeGlobalTemplate": "true"},
+	}
+	global.Data = map[string][]byte{"user": []byte("{{ .Namespace }}-reader")}
+	_, _ = config.Client.Clientset.CoreV1().Secrets("myapp").Create(&global)
+
+	broken := global
+	broken.ObjectMeta = metav1.ObjectMeta{
+		Name:        "broken-global",
+		Namespace:   "myapp",
+		Annotations: map[string]string{"MakeGlobal": "true", "MakeGlobalTemplate": "true"},
+	}
+	broken.Data = map[string][]byte{"user": []byte("{{ .Namespace ")}
+	_, _ = config.Client.Clientset.CoreV1().Secrets("myapp").Create(&broken)
+
+	runr := runner.NewRunner(&config)
+	require.NotNil(runr)
+	defer runr.Close()
+
+	err := runr.Start()
+	require.NoError(err)
+
+	res, err := config.Client.Clientset.CoreV1().Secrets("default").Get(global.Name, metav1.GetOptions{})
+	require.NoError(err)
+	require.Equal("default-reader", string(res.Data["user"]))
+
+	// a template that does not parse is skipped
+	_, err = config.Client.Clientset.CoreV1().Secrets("default").Get(broken.Name, metav1.GetOptions{})
+	require.Error(err)
+}